@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 
 	cpuHandler "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/handlers"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
@@ -56,7 +58,6 @@ func main() {
 		fmt.Println("Error al convertir a int:", err)
 		return
 	}
-	services.ConnectToKernel(cpuId, models.CpuConfig)
 
 	//Solicita información a la Memoria
 	if err := services.RequestMemoryConfig(); err != nil {
@@ -64,18 +65,58 @@ func main() {
 	}
 
 	//Inicializar
-	services.InitTLB()
+	services.InitCores()
 	services.InitCache()
 	slog.Debug(fmt.Sprintf("Configuración cargada - CacheEntries: %d - Algoritmo: %s", models.CpuConfig.CacheEntries, models.CpuConfig.CacheReplacement))
 
 	http.HandleFunc("GET /", handlers.HandshakeHandler(fmt.Sprintf("Bienvenido al módulo de CPU%s", idCpu)))
 	http.HandleFunc("GET /cpu", handlers.HandshakeHandler("Cpu en funcionamiento 🚀"))
-	http.HandleFunc("POST /cpu/exec", cpuHandler.ExecuteProcessHandler(models.CpuConfig))
+	//Se cierra al apagar la CPU: corta el heartbeat y los reintentos de informes al Kernel
+	stop := make(chan struct{})
+
+	http.HandleFunc("POST /cpu/exec", cpuHandler.ExecuteProcessHandler(models.CpuConfig, stop))
 	http.HandleFunc("POST /cpu/interrupt", cpuHandler.InterruptProcessHandler())
+	http.HandleFunc("POST /cpu/invalidate", cpuHandler.InvalidateProcessHandler())
 
-	err = server.InitServer(models.CpuConfig.PortCpu)
-	if err != nil {
-		slog.Error(fmt.Sprintf("error initializing server: %v", err))
-		panic(err)
-	}
+	//Estado de la TLB y la caché
+	http.HandleFunc("GET /cpu/tlb", cpuHandler.TLBStatusHandler())
+	http.HandleFunc("GET /cpu/cache", cpuHandler.CacheStatusHandler())
+
+	//Estado de los núcleos, para el chequeo de salud del Kernel
+	http.HandleFunc("GET /cpu/status", cpuHandler.CoreStatusHandler())
+
+	//Trazas de ejecución
+	http.HandleFunc("GET /cpu/trace", cpuHandler.TraceHandler())
+	http.HandleFunc("POST /cpu/trace/export", cpuHandler.ExportTraceHandler())
+
+	//Debugger
+	http.HandleFunc("GET /cpu/debug/breakpoints", cpuHandler.ListBreakpointsHandler())
+	http.HandleFunc("POST /cpu/debug/breakpoints", cpuHandler.AddBreakpointHandler())
+	http.HandleFunc("DELETE /cpu/debug/breakpoints", cpuHandler.RemoveBreakpointHandler())
+	http.HandleFunc("POST /cpu/debug/pause", cpuHandler.PauseHandler())
+	http.HandleFunc("POST /cpu/debug/step", cpuHandler.StepHandler())
+	http.HandleFunc("POST /cpu/debug/continue", cpuHandler.ContinueHandler())
+	http.HandleFunc("GET /cpu/debug/state", cpuHandler.DebugStateHandler())
+
+	go func() {
+		err := server.InitServer(models.CpuConfig.PortCpu)
+		if err != nil {
+			slog.Error(fmt.Sprintf("error initializing server: %v", err))
+			panic(err)
+		}
+	}()
+
+	//Cada núcleo se registra en el Kernel como una CPU independiente, con el servidor ya escuchando
+	go services.MaintainKernelRegistration(cpuId, models.CpuConfig, stop)
+
+	//Al cerrarse, la CPU se da de baja en el Kernel para que no le envíe más procesos
+	signal.Notify(models.Shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-models.Shutdown
+	slog.Debug("Señal recibida, cerrando módulo CPU", "signal", sig)
+
+	close(stop)
+	services.NotifyDisconnection(cpuId, models.CpuConfig)
+
+	os.Exit(0)
 }
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
@@ -13,104 +15,276 @@ import (
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/server"
 )
 
-func ExecuteProcessHandler(cpuConfig *models.Config) func(http.ResponseWriter, *http.Request) {
+// getCore obtiene el núcleo indicado en el query param "core". Si no se indica se usa el núcleo 0.
+func getCore(r *http.Request) (*services.Core, error) {
+	coreId := 0
+	if coreStr := r.URL.Query().Get("core"); coreStr != "" {
+		id, err := strconv.Atoi(coreStr)
+		if err != nil {
+			return nil, fmt.Errorf("núcleo inválido: %s", coreStr)
+		}
+		coreId = id
+	}
+
+	core, found := services.GetCore(coreId)
+	if !found {
+		return nil, fmt.Errorf("no existe el núcleo %d", coreId)
+	}
+	return core, nil
+}
+
+// ExecuteProcessHandler recibe un proceso para ejecutar en el núcleo. Responde apenas acepta el proceso;
+// el resultado de la ráfaga se informa al Kernel cuando el proceso abandona la CPU.
+func ExecuteProcessHandler(cpuConfig *models.Config, stop <-chan struct{}) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var instructionRequest memoriaModel.InstructionRequest
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var executeRequest kernelModel.PCBExecuteRequest
 
-		err := json.NewDecoder(r.Body).Decode(&instructionRequest)
+		err = json.NewDecoder(r.Body).Decode(&executeRequest)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		request := memoriaModel.InstructionRequest{
-			Pid:      instructionRequest.Pid,
-			PC:       instructionRequest.PC,
-			PathName: instructionRequest.PathName,
+			Pid: executeRequest.PID,
+			PC:  executeRequest.PC,
 		}
 
-		models.CpuRegisters.PC = uint(request.PC)
+		if !core.StartExecution(request.Pid, request.PC) {
+			http.Error(w, fmt.Sprintf("el núcleo %d ya está ejecutando el PID %d", core.Id, core.ExecutingPID()), http.StatusConflict)
+			return
+		}
+		slog.Debug(fmt.Sprintf("Núcleo %d - Ejecutando PID %d desde PC %d (despacho %d)", core.Id, request.Pid, request.PC, executeRequest.DispatchId))
+
+		go func() {
+			released := false
+			// Si el ciclo de instrucción entra en pánico el núcleo no debe quedar ocupado ni el Kernel esperando
+			// el resultado: el proceso se finaliza, volver a ejecutarlo repetiría la misma instrucción.
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					slog.Error("Pánico ejecutando el proceso. Se finaliza.", "pid", request.Pid, "core", core.Id, "error", recovered)
+					if !released {
+						core.FinishExecution()
+					}
+					services.ReportBurstCompletion(kernelModel.PCBExecuteRequest{
+						PID:           request.Pid,
+						PC:            int(core.Registers.PC),
+						StatusCodePCB: kernelModel.NeedFinish,
+						DispatchId:    executeRequest.DispatchId,
+					}, cpuConfig, stop)
+				}
+			}()
 
-		executionStartTime := time.Now()
+			response := executeBurst(core, request, cpuConfig)
+			response.DispatchId = executeRequest.DispatchId
+			// El núcleo se libera antes de informar, el Kernel puede despacharle otro proceso apenas reciba el resultado.
+			core.FinishExecution()
+			released = true
+			services.ReportBurstCompletion(response, cpuConfig, stop)
+		}()
 
-		var isFinished, isBlocked, isSyscall bool = false, false, false
-		var syscallRequest kernelModel.SyscallRequest
+		w.WriteHeader(http.StatusOK)
+	}
+}
 
-		models.InterruptControl.PID = int(instructionRequest.Pid)
-		for !models.InterruptControl.InterruptPending && !isFinished && !isBlocked {
-			fetchResult := services.Fetch(request, cpuConfig)
+// executeBurst ejecuta el ciclo de instrucción hasta que el proceso abandona la CPU y arma el resultado para el Kernel.
+func executeBurst(core *services.Core, request memoriaModel.InstructionRequest, cpuConfig *models.Config) kernelModel.PCBExecuteRequest {
+	executionStartTime := time.Now()
 
-			if fetchResult.Instruction == "" {
-				w.WriteHeader(http.StatusNotFound)
-				return
-			}
+	var isFinished, isBlocked, isSyscall bool = false, false, false
+	var syscallRequest kernelModel.SyscallRequest
+	var interrupt models.Interrupt
+	var interrupted bool
 
-			services.DecodeAndExecute(instructionRequest.Pid, fetchResult.Instruction, cpuConfig, &isFinished, &isBlocked, &isSyscall, &syscallRequest)
+	for !interrupted && !isFinished && !isBlocked {
+		fetchResult := services.Fetch(core, request, cpuConfig)
 
-			if !isFinished && fetchResult.IsLast {
-				isFinished = fetchResult.IsLast
+		if fetchResult.Instruction == "" {
+			slog.Error("No se obtuvo la instrucción. Se devuelve el proceso al Kernel.", "pid", request.Pid, "pc", request.PC)
+			services.FlushInstructionBuffer(core)
+			return kernelModel.PCBExecuteRequest{
+				PID:           request.Pid,
+				PC:            request.PC,
+				StatusCodePCB: kernelModel.NeedReplan,
+				ExecutionTime: float32(time.Since(executionStartTime).Milliseconds()),
 			}
+		}
 
-			request.PC = int(models.CpuRegisters.PC)
+		if !core.Debugger.BeforeExecute(request.Pid, request.PC, fetchResult.Instruction) {
+			// Una interrupción despertó la pausa: se atiende sin ejecutar la instrucción.
+			interrupt, interrupted = core.CheckInterrupt()
+			continue
 		}
 
-		executionTime := float32(time.Since(executionStartTime).Milliseconds())
+		services.BeginTrace(core, request.Pid, request.PC, fetchResult.Instruction)
+		services.DecodeAndExecute(core, request.Pid, fetchResult.Instruction, cpuConfig, &isFinished, &isBlocked, &isSyscall, &syscallRequest)
+		services.EndTrace(core)
 
-		response := kernelModel.PCBExecuteRequest{
-			PID:           request.Pid,
-			PC:            request.PC,
-			ExecutionTime: executionTime,
+		if !isFinished && fetchResult.IsLast {
+			isFinished = fetchResult.IsLast
 		}
 
-		if models.InterruptControl.InterruptPending {
-			response.StatusCodePCB = kernelModel.NeedInterrupt
-			slog.Debug("ExecuteProcessHandler need interrupt")
-		}
+		request.PC = int(core.Registers.PC)
+		core.Interrupts.SetPC(request.PC)
 
-		if isBlocked && !isSyscall {
-			response.StatusCodePCB = kernelModel.NeedReplan
-			slog.Debug("ExecuteProcessHandler need re-plan")
+		// Las interrupciones se atienden al final del ciclo, solo si el proceso sigue en CPU.
+		if !isFinished && !isBlocked {
+			interrupt, interrupted = core.CheckInterrupt()
 		}
+	}
 
-		if isSyscall && !isFinished {
-			response.StatusCodePCB = kernelModel.NeedExecuteSyscall
-			response.SyscallRequest = syscallRequest
-			slog.Debug("ExecuteProcessHandler need execute syscall")
-		}
+	executionTime := float32(time.Since(executionStartTime).Milliseconds())
 
-		if isFinished && !isBlocked && !models.InterruptControl.InterruptPending {
-			if isBlocked {
-				response.SyscallRequest = syscallRequest
-			}
-			response.StatusCodePCB = kernelModel.NeedFinish
-			slog.Debug("ExecuteProcessHandler need finish")
-		}
+	if isFinished {
+		services.ExportTraceOnExit(request.Pid)
+	}
+
+	// Con flush_on_deschedule el proceso desalojado no deja páginas ni traducciones en esta CPU.
+	if interrupted && models.CpuConfig.FlushOnDeschedule {
+		services.InvalidateProcess(request.Pid)
+	}
+
+	// Se informan los fetches antes de responder, el Kernel puede finalizar el proceso apenas reciba la respuesta.
+	services.FlushInstructionBuffer(core)
+
+	response := kernelModel.PCBExecuteRequest{
+		PID:           request.Pid,
+		PC:            request.PC,
+		ExecutionTime: executionTime,
+	}
+
+	if interrupted {
+		response.StatusCodePCB = kernelModel.NeedInterrupt
+		response.InterruptType = interrupt.Type
+		slog.Debug("ExecuteProcessHandler need interrupt", "type", interrupt.Type)
+	}
+
+	if isBlocked && !isSyscall {
+		response.StatusCodePCB = kernelModel.NeedReplan
+		slog.Debug("ExecuteProcessHandler need re-plan")
+	}
+
+	if isSyscall && !isFinished {
+		response.StatusCodePCB = kernelModel.NeedExecuteSyscall
+		response.SyscallRequest = syscallRequest
+		slog.Debug("ExecuteProcessHandler need execute syscall")
+	}
 
-		models.InterruptControl.InterruptPending = false
-		server.SendJsonResponse(w, response)
+	if isFinished && !isBlocked && !interrupted {
+		response.StatusCodePCB = kernelModel.NeedFinish
+		slog.Debug("ExecuteProcessHandler need finish")
 	}
+
+	return response
 }
 
 func InterruptProcessHandler() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var pid int
-		if err := json.NewDecoder(r.Body).Decode(&pid); err != nil {
-			http.Error(w, "PID inválido", http.StatusBadRequest)
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		slog.Debug("Interrupción recibida", slog.Int("pid", pid))
+		var interrupt models.Interrupt
+		if err := json.NewDecoder(r.Body).Decode(&interrupt); err != nil {
+			http.Error(w, "Interrupción inválida", http.StatusBadRequest)
+			return
+		}
+		if !services.IsValidInterruptType(interrupt.Type) {
+			http.Error(w, fmt.Sprintf("Tipo de interrupción desconocido: %s", interrupt.Type), http.StatusBadRequest)
+			return
+		}
+
+		slog.Debug("Interrupción recibida", slog.Int("pid", interrupt.PID), slog.String("type", string(interrupt.Type)), slog.Int("core", core.Id))
 		slog.Info("##Llega interrupción al puerto Interrupt")
 
-		if pid == models.InterruptControl.PID {
-			slog.Debug("Interrupción necesaria. Marcando para desalojo.", slog.Int("pid", pid))
-			models.InterruptControl.InterruptPending = true
+		if core.RequestInterrupt(interrupt) {
+			slog.Debug("Interrupción encolada.", slog.Int("pid", interrupt.PID), slog.String("type", string(interrupt.Type)))
 			w.WriteHeader(http.StatusOK)
 		} else {
-			//slog.Error("No existe ese proceso ejecutandose en esta cpu para interrumpirlo", slog.Int("pid", pid))
-			slog.Warn("Se recibió interrupción para un proceso que ya no está en ejecución. Ignorando.", "pid_a_interrumpir", pid, "pid_actual", models.InterruptControl.PID)
+			slog.Warn("Se recibió interrupción para un proceso que ya no está en ejecución. Ignorando.", "pid_a_interrumpir", interrupt.PID, "pid_actual", core.ExecutingPID())
 			w.WriteHeader(http.StatusOK)
 		}
 
 	}
 }
+
+// TraceHandler devuelve las últimas instrucciones ejecutadas por un proceso. La de un proceso finalizado
+// ya no está en memoria: queda en el archivo exportado a trace_path.
+func TraceHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pid, err := strconv.ParseUint(r.URL.Query().Get("pid"), 10, 64)
+		if err != nil {
+			http.Error(w, "PID inválido", http.StatusBadRequest)
+			return
+		}
+		server.SendJsonResponse(w, services.GetTrace(uint(pid)))
+	}
+}
+
+// ExportTraceHandler exporta la traza de un proceso a un archivo JSON Lines.
+func ExportTraceHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pid, err := strconv.ParseUint(r.URL.Query().Get("pid"), 10, 64)
+		if err != nil {
+			http.Error(w, "PID inválido", http.StatusBadRequest)
+			return
+		}
+		path, err := services.ExportTrace(uint(pid))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		server.SendJsonResponse(w, path)
+	}
+}
+
+// TLBStatusHandler devuelve las entradas y los contadores de hits, misses y evicciones de cada TLB.
+func TLBStatusHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.SendJsonResponse(w, services.GetTLBStatus())
+	}
+}
+
+// CoreStatusHandler devuelve el proceso y el PC de cada núcleo. Es el chequeo de salud del Kernel.
+func CoreStatusHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.SendJsonResponse(w, services.GetCoreStatus())
+	}
+}
+
+// CacheStatusHandler devuelve la configuración de la caché y los contadores de cada proceso.
+func CacheStatusHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.SendJsonResponse(w, services.Cache.Status())
+	}
+}
+
+// InvalidateProcessHandler escribe en Memoria las páginas modificadas de un proceso y descarta su caché y TLB.
+// El Kernel lo usa cuando el proceso se despacha a otra CPU o antes de enviarlo a SWAP.
+func InvalidateProcessHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var pid uint
+		if err := json.NewDecoder(r.Body).Decode(&pid); err != nil {
+			http.Error(w, "PID inválido", http.StatusBadRequest)
+			return
+		}
+
+		slog.Debug("Invalidación de caché y TLB recibida", slog.Int("pid", int(pid)), slog.Int("core", core.Id))
+		services.InvalidateProcess(pid)
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/services"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/server"
+)
+
+// AddBreakpointHandler agrega un breakpoint en (PID, PC).
+func AddBreakpointHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var breakpoint models.Breakpoint
+		if err := json.NewDecoder(r.Body).Decode(&breakpoint); err != nil {
+			http.Error(w, "Breakpoint inválido", http.StatusBadRequest)
+			return
+		}
+		services.AddBreakpoint(breakpoint)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RemoveBreakpointHandler elimina un breakpoint en (PID, PC).
+func RemoveBreakpointHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var breakpoint models.Breakpoint
+		if err := json.NewDecoder(r.Body).Decode(&breakpoint); err != nil {
+			http.Error(w, "Breakpoint inválido", http.StatusBadRequest)
+			return
+		}
+		services.RemoveBreakpoint(breakpoint)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// ListBreakpointsHandler devuelve los breakpoints activos.
+func ListBreakpointsHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.SendJsonResponse(w, services.GetBreakpoints())
+	}
+}
+
+// PauseHandler pausa el núcleo antes de la próxima instrucción.
+func PauseHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		core.Debugger.Pause()
+		slog.Debug("Pausa solicitada por debugger", slog.Int("core", core.Id))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// StepHandler ejecuta una instrucción en un núcleo pausado y lo vuelve a pausar.
+func StepHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := core.Debugger.Step(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// ContinueHandler reanuda un núcleo pausado.
+func ContinueHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := core.Debugger.Continue(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// DebugStateHandler devuelve registros, TLB y caché del proceso en ejecución en el núcleo.
+func DebugStateHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		core, err := getCore(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		server.SendJsonResponse(w, services.GetDebugState(core))
+	}
+}
@@ -11,4 +11,28 @@ type CacheEntry struct {
 	ModifiedBit bool   // Bit de Modificación (M): true si la página fue escrita en caché
 	LockerBit   bool   // Bit de bloqueo: true esta siendo leida o escrita por lo que no puede ser reemplazada
 	//TiempoCarga time.Time // Momento en que la página fue cargada en caché
+	LoadedAt int64 // Orden de carga, para la cola FIFO de 2Q
+	LastUsed int64 // Último acceso, para LRU
+	UseCount int   // Cantidad de accesos, para LFU
+	Hot      bool  // 2Q: true si la página está en la cola Am (referenciada más de una vez)
+}
+
+// CachePidStats acumula los accesos a la caché de un proceso. Las evicciones se cuentan
+// para el proceso dueño de la página reemplazada.
+type CachePidStats struct {
+	Hits           int     `json:"hits"`
+	Misses         int     `json:"misses"`
+	HitRatio       float64 `json:"hit_ratio"`
+	Evictions      int     `json:"evictions"`
+	DirtyEvictions int     `json:"dirty_evictions"`
+	MemoryUpdates  int     `json:"memory_updates"`
+}
+
+// CacheStatus es la foto de la caché que se devuelve en GET /cpu/cache.
+type CacheStatus struct {
+	Algorithm   string                 `json:"algorithm"`
+	WritePolicy string                 `json:"write_policy"`
+	MaxEntries  int                    `json:"max_entries"`
+	Entries     int                    `json:"entries"`
+	Stats       map[uint]CachePidStats `json:"stats"`
 }
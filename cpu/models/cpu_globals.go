@@ -1,29 +1,67 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
-	PortCpu          int    `json:"port_cpu"`
-	IpCpu            string `json:"ip_cpu"`
-	IpMemory         string `json:"ip_memory"`
-	PortMemory       int    `json:"port_memory"`
-	IpKernel         string `json:"ip_kernel"`
-	PortKernel       int    `json:"port_kernel"`
-	TlbEntries       int    `json:"tlb_entries"`
-	TlbReplacement   string `json:"tlb_replacement"`
-	CacheEntries     int    `json:"cache_entries"`
-	CacheReplacement string `json:"cache_replacement"`
-	CacheDelay       int    `json:"cache_delay"`
-	LogLevel         string `json:"log_level"`
+	PortCpu               int    `json:"port_cpu"`
+	IpCpu                 string `json:"ip_cpu"`
+	IpMemory              string `json:"ip_memory"`
+	PortMemory            int    `json:"port_memory"`
+	IpKernel              string `json:"ip_kernel"`
+	PortKernel            int    `json:"port_kernel"`
+	TlbEntries            int    `json:"tlb_entries"`
+	TlbReplacement        string `json:"tlb_replacement"`
+	TlbOptTrace           string `json:"tlb_opt_trace"`
+	CacheEntries          int    `json:"cache_entries"`
+	CacheReplacement      string `json:"cache_replacement"`
+	CacheWritePolicy      string `json:"cache_write_policy"`
+	FlushOnDeschedule     bool   `json:"flush_on_deschedule"`
+	CacheDelay            int    `json:"cache_delay"`
+	LogLevel              string `json:"log_level"`
+	Cores                 int    `json:"cpu_cores"`
+	TlbPerCore            bool   `json:"tlb_per_core"`
+	InstructionBufferSize int    `json:"instruction_buffer_size"`
+	TraceEntries          int    `json:"trace_entries"`
+	TracePath             string `json:"trace_path"`
+	KernelHeartbeat       int    `json:"kernel_heartbeat"` // ms entre registros en el Kernel (0 = solo al iniciar)
 }
 
 var CpuConfig *Config
 
+var Shutdown = make(chan os.Signal, 1)
+
+// BootId identifica este arranque del módulo: si el Kernel lo recibe distinto en la misma dirección, la CPU se reinició.
+var BootId = strconv.FormatInt(time.Now().UnixNano(), 36)
+
 type TLBEntry struct {
 	PID         uint
 	PageNumber  int
 	FrameNumber int
 	LastUsed    int64 //contador para LRU
+	UseCount    int   //cantidad de accesos para LFU
+	Referenced  bool  //bit de uso para CLOCK
+}
+
+// TLBPidStats acumula los accesos a la TLB de un proceso. Las evicciones se cuentan
+// para el proceso dueño de la entrada reemplazada.
+type TLBPidStats struct {
+	Hits      int `json:"hits"`
+	Misses    int `json:"misses"`
+	Evictions int `json:"evictions"`
+}
+
+// TLBStatus es la foto de una TLB que se devuelve en GET /cpu/tlb.
+type TLBStatus struct {
+	Cores     []int                `json:"cores"`
+	Algorithm string               `json:"algorithm"`
+	MaxSize   int                  `json:"max_size"`
+	Entries   []TLBEntry           `json:"entries"`
+	Stats     map[uint]TLBPidStats `json:"stats"`
 }
 
 type MemoryConfig struct {
@@ -44,22 +82,44 @@ type Registers struct {
 	PC uint
 }
 
-var CpuRegisters Registers
+// InterruptType identifica el motivo de una interrupción enviada a la CPU.
+type InterruptType string
+
+const (
+	InterruptQuantum InterruptType = "QUANTUM" // Fin de quantum
+	InterruptPreempt InterruptType = "PREEMPT" // Desalojo por un proceso de mayor prioridad (SRT)
+	InterruptKill    InterruptType = "KILL"    // Finalización forzada del proceso
+	InterruptSuspend InterruptType = "SUSPEND" // Suspensión del proceso (pasa a SWAP)
+	InterruptDebug   InterruptType = "DEBUG"   // Pausa del núcleo, no devuelve el proceso al Kernel
+)
+
+// InterruptPriority define qué interrupción se atiende primero cuando hay varias pendientes.
+var InterruptPriority = map[InterruptType]int{
+	InterruptKill:    5,
+	InterruptSuspend: 4,
+	InterruptPreempt: 3,
+	InterruptQuantum: 2,
+	InterruptDebug:   1,
+}
 
-var InterruptControl = InterruptData{
-	InterruptPending: false,
-	PID:              -1,
+type Interrupt struct {
+	PID  int           `json:"pid"`
+	Type InterruptType `json:"type"`
 }
 
-type InterruptData struct {
-	InterruptPending bool
-	PID              int
+// CoreStatus es lo que ejecuta un núcleo, informado al Kernel en el chequeo de salud.
+type CoreStatus struct {
+	Core int `json:"core"`
+	PID  int `json:"pid"` // -1 si el núcleo está libre.
+	PC   int `json:"pc"`  // Próxima instrucción a ejecutar.
 }
 
 type CpuN struct {
 	Port         int
 	Ip           string
 	Id           int
+	Core         int
+	BootId       string // Arranque del módulo que registró el núcleo.
 	IsFree       bool
 	PIDExecuting uint
 	PIDRafaga    float32
@@ -84,6 +144,43 @@ type ReadRequest struct {
 	Size            int  `json:"size"`
 }
 
+// Para el debugger
+type Breakpoint struct {
+	PID uint `json:"pid"`
+	PC  int  `json:"pc"`
+}
+
+type DebugCacheEntry struct {
+	PageNumber  int    `json:"page_number"`
+	Frame       int    `json:"frame"`
+	Content     string `json:"content"`
+	UseBit      bool   `json:"use_bit"`
+	ModifiedBit bool   `json:"modified_bit"`
+}
+
+type DebugState struct {
+	Core        int               `json:"core"`
+	PID         int               `json:"pid"`
+	PC          uint              `json:"pc"`
+	Paused      bool              `json:"paused"`
+	Instruction string            `json:"instruction"`
+	TLB         []TLBEntry        `json:"tlb"`
+	Cache       []DebugCacheEntry `json:"cache"`
+}
+
+// TraceRecord es una instrucción ejecutada. Las direcciones valen -1 si la instrucción no accede a memoria.
+type TraceRecord struct {
+	PID             uint      `json:"pid"`
+	Core            int       `json:"core"`
+	PC              int       `json:"pc"`
+	Instruction     string    `json:"instruction"`
+	LogicalAddress  int       `json:"logical_address"`
+	PhysicalAddress int       `json:"physical_address"`
+	TLB             string    `json:"tlb,omitempty"`
+	Cache           string    `json:"cache,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
 // DEFINICION DE ERRORES
 var ErrInvalidInstruction = errors.New("invalid instruction")
 var ErrInvalidAddress = errors.New("invalid address")
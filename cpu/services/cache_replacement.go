@@ -0,0 +1,110 @@
+package services
+
+// Algoritmos de reemplazo soportados por la caché de páginas.
+var cacheAlgorithms = map[string]bool{
+	"CLOCK":   true,
+	"CLOCK-M": true,
+	"LRU":     true,
+	"LFU":     true,
+	"2Q":      true,
+}
+
+// findVictimIndexLRU elige la página usada hace más tiempo. Las páginas bloqueadas no se reemplazan.
+func (cache *PageCache) findVictimIndexLRU() int {
+	victimIndex := -1
+	for i, entry := range cache.Entries {
+		if entry.LockerBit {
+			continue
+		}
+		if victimIndex == -1 || entry.LastUsed < cache.Entries[victimIndex].LastUsed {
+			victimIndex = i
+		}
+	}
+	if victimIndex == -1 {
+		return cache.ClockPointer
+	}
+	return victimIndex
+}
+
+// findVictimIndexLFU elige la página menos accedida. Ante un empate se reemplaza la usada hace más tiempo.
+func (cache *PageCache) findVictimIndexLFU() int {
+	victimIndex := -1
+	for i, entry := range cache.Entries {
+		if entry.LockerBit {
+			continue
+		}
+		if victimIndex == -1 {
+			victimIndex = i
+			continue
+		}
+		victim := cache.Entries[victimIndex]
+		if entry.UseCount < victim.UseCount || (entry.UseCount == victim.UseCount && entry.LastUsed < victim.LastUsed) {
+			victimIndex = i
+		}
+	}
+	if victimIndex == -1 {
+		return cache.ClockPointer
+	}
+	return victimIndex
+}
+
+// findVictimIndex2Q implementa 2Q: las páginas nuevas entran a la cola FIFO A1in y solo pasan a la
+// cola LRU Am si se vuelven a pedir después de ser desalojadas (siguen recordadas en A1out).
+// Mientras A1in supere su cupo (un cuarto de la caché) la víctima sale de ahí.
+func (cache *PageCache) findVictimIndex2Q() int {
+	a1inCount := 0
+	for _, entry := range cache.Entries {
+		if !entry.Hot {
+			a1inCount++
+		}
+	}
+
+	a1inLimit := cache.MaxEntries / 4
+	if a1inLimit < 1 {
+		a1inLimit = 1
+	}
+	fromA1in := a1inCount > a1inLimit || a1inCount == len(cache.Entries)
+
+	victimIndex := -1
+	for i, entry := range cache.Entries {
+		if entry.LockerBit || entry.Hot == fromA1in {
+			continue
+		}
+		if victimIndex == -1 {
+			victimIndex = i
+			continue
+		}
+		victim := cache.Entries[victimIndex]
+		if (fromA1in && entry.LoadedAt < victim.LoadedAt) || (!fromA1in && entry.LastUsed < victim.LastUsed) {
+			victimIndex = i
+		}
+	}
+	if victimIndex == -1 {
+		return cache.findVictimIndexLRU()
+	}
+	return victimIndex
+}
+
+// rememberGhost agrega una página desalojada de A1in a la lista A1out (la mitad de la caché como máximo).
+func (cache *PageCache) rememberGhost(pid uint, pageNumber int) {
+	limit := cache.MaxEntries / 2
+	if limit < 1 {
+		limit = 1
+	}
+	cache.ghosts = append(cache.ghosts, getEntryKey(pid, pageNumber))
+	if len(cache.ghosts) > limit {
+		cache.ghosts = cache.ghosts[len(cache.ghosts)-limit:]
+	}
+}
+
+// forgetGhost indica si la página estaba en A1out y la quita de la lista.
+func (cache *PageCache) forgetGhost(pid uint, pageNumber int) bool {
+	key := getEntryKey(pid, pageNumber)
+	for i, ghost := range cache.ghosts {
+		if ghost == key {
+			cache.ghosts = append(cache.ghosts[:i], cache.ghosts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
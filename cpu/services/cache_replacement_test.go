@@ -0,0 +1,215 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// newTestCache arma una caché vacía sin pasar por la configuración global.
+func newTestCache(algorithm string, maxEntries int) *PageCache {
+	return &PageCache{
+		Entries:     make([]models.CacheEntry, 0, maxEntries),
+		MaxEntries:  maxEntries,
+		Algorithm:   algorithm,
+		WritePolicy: WriteBack,
+		Stats:       make(map[uint]*models.CachePidStats),
+		PageMap: make(map[struct {
+			PID        uint
+			PageNumber int
+		}]int),
+	}
+}
+
+// cacheAccess simula un acceso del PID 1 a la página igual que Get y Put, sin la demora configurada.
+// Las páginas quedan limpias, así el reemplazo nunca necesita escribir en Memoria.
+func cacheAccess(cache *PageCache, page int) {
+	key := getEntryKey(1, page)
+	if index, found := cache.PageMap[key]; found {
+		cache.Counter++
+		cache.Entries[index].UseBit = true
+		cache.Entries[index].LastUsed = cache.Counter
+		cache.Entries[index].UseCount++
+		return
+	}
+	if len(cache.Entries) >= cache.MaxEntries {
+		cache.replaceVictim(1, page, page, make([]byte, 4))
+		return
+	}
+	cache.Entries = append(cache.Entries, cache.newEntry(1, page, page, make([]byte, 4)))
+	cache.PageMap[key] = len(cache.Entries) - 1
+}
+
+func cacheContains(cache *PageCache, page int) bool {
+	_, found := cache.PageMap[getEntryKey(1, page)]
+	return found
+}
+
+func TestPageCache_FindVictimIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		find     func(*PageCache) int
+		entries  []models.CacheEntry
+		expected int
+	}{
+		{
+			name: "LRU reemplaza la usada hace más tiempo",
+			find: (*PageCache).findVictimIndexLRU,
+			entries: []models.CacheEntry{
+				{PageNumber: 0, LastUsed: 4},
+				{PageNumber: 1, LastUsed: 9},
+				{PageNumber: 2, LastUsed: 1},
+			},
+			expected: 2,
+		},
+		{
+			name: "LRU no reemplaza páginas bloqueadas",
+			find: (*PageCache).findVictimIndexLRU,
+			entries: []models.CacheEntry{
+				{PageNumber: 0, LastUsed: 4},
+				{PageNumber: 1, LastUsed: 9},
+				{PageNumber: 2, LastUsed: 1, LockerBit: true},
+			},
+			expected: 0,
+		},
+		{
+			name: "LFU reemplaza la menos accedida",
+			find: (*PageCache).findVictimIndexLFU,
+			entries: []models.CacheEntry{
+				{PageNumber: 0, UseCount: 3, LastUsed: 1},
+				{PageNumber: 1, UseCount: 1, LastUsed: 8},
+				{PageNumber: 2, UseCount: 2, LastUsed: 2},
+			},
+			expected: 1,
+		},
+		{
+			name: "LFU desempata por la usada hace más tiempo",
+			find: (*PageCache).findVictimIndexLFU,
+			entries: []models.CacheEntry{
+				{PageNumber: 0, UseCount: 1, LastUsed: 6},
+				{PageNumber: 1, UseCount: 1, LastUsed: 3},
+				{PageNumber: 2, UseCount: 2, LastUsed: 1},
+			},
+			expected: 1,
+		},
+		{
+			name: "2Q desaloja de A1in por orden de carga mientras supera su cupo",
+			find: (*PageCache).findVictimIndex2Q,
+			entries: []models.CacheEntry{
+				{PageNumber: 0, LoadedAt: 5, LastUsed: 1, Hot: true},
+				{PageNumber: 1, LoadedAt: 3, LastUsed: 9},
+				{PageNumber: 2, LoadedAt: 2, LastUsed: 8},
+				{PageNumber: 3, LoadedAt: 4, LastUsed: 7},
+			},
+			expected: 2,
+		},
+		{
+			name: "2Q desaloja de Am por LRU cuando A1in está dentro del cupo",
+			find: (*PageCache).findVictimIndex2Q,
+			entries: []models.CacheEntry{
+				{PageNumber: 0, LoadedAt: 1, LastUsed: 6, Hot: true},
+				{PageNumber: 1, LoadedAt: 2, LastUsed: 2, Hot: true},
+				{PageNumber: 2, LoadedAt: 3, LastUsed: 1},
+				{PageNumber: 3, LoadedAt: 4, LastUsed: 5, Hot: true},
+			},
+			expected: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := newTestCache("", len(test.entries))
+			cache.Entries = append(cache.Entries, test.entries...)
+			if got := test.find(cache); got != test.expected {
+				t.Errorf("Expected victim %d, got %d", test.expected, got)
+			}
+		})
+	}
+}
+
+// Una página desalojada de A1in que se vuelve a pedir mientras sigue en A1out entra directo a Am.
+func TestPageCache_2QGhostPromotesToHot(t *testing.T) {
+	cache := newTestCache("2Q", 4)
+	for _, page := range []int{1, 2, 3, 4, 5} {
+		cacheAccess(cache, page)
+	}
+
+	if cacheContains(cache, 1) {
+		t.Fatalf("Expected page 1 to be evicted from A1in")
+	}
+	if len(cache.ghosts) != 1 || cache.ghosts[0] != getEntryKey(1, 1) {
+		t.Fatalf("Expected page 1 in A1out, got %v", cache.ghosts)
+	}
+
+	cacheAccess(cache, 1)
+
+	index, found := cache.PageMap[getEntryKey(1, 1)]
+	if !found {
+		t.Fatalf("Expected page 1 back in the cache")
+	}
+	if !cache.Entries[index].Hot {
+		t.Errorf("Expected page 1 to enter Am")
+	}
+	if cacheContains(cache, 2) {
+		t.Errorf("Expected page 2 to be the next A1in victim")
+	}
+	for _, ghost := range cache.ghosts {
+		if ghost == getEntryKey(1, 1) {
+			t.Errorf("Expected page 1 to leave A1out")
+		}
+	}
+}
+
+func TestPageCache_2QGhostListIsBounded(t *testing.T) {
+	cache := newTestCache("2Q", 4)
+	for page := 0; page < 5; page++ {
+		cache.rememberGhost(1, page)
+	}
+
+	if len(cache.ghosts) != 2 {
+		t.Fatalf("Expected 2 ghosts, got %d", len(cache.ghosts))
+	}
+	if cache.forgetGhost(1, 0) {
+		t.Errorf("Expected the oldest ghost to be dropped")
+	}
+	if !cache.forgetGhost(1, 4) {
+		t.Errorf("Expected the newest ghost to be remembered")
+	}
+	if cache.forgetGhost(1, 4) {
+		t.Errorf("Expected forgetGhost to remove the ghost")
+	}
+}
+
+func TestPageCache_WriteMarksModifiedOnlyWithWriteBack(t *testing.T) {
+	tests := []struct {
+		policy   string
+		modified bool
+	}{
+		{WriteBack, true},
+		{WriteThru, false},
+		{WriteAround, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			cache := newTestCache("LRU", 1)
+			cache.WritePolicy = test.policy
+			cacheAccess(cache, 0)
+
+			if !cache.Write(1, 0, 1, []byte("ab")) {
+				t.Fatalf("Expected the write to hit the cache")
+			}
+			if got := string(cache.Entries[0].Content[1:3]); got != "ab" {
+				t.Errorf("Expected content %q, got %q", "ab", got)
+			}
+			if cache.Entries[0].ModifiedBit != test.modified {
+				t.Errorf("Expected modified %v, got %v", test.modified, cache.Entries[0].ModifiedBit)
+			}
+		})
+	}
+
+	cache := newTestCache("LRU", 1)
+	if cache.Write(1, 0, 0, []byte("a")) {
+		t.Errorf("Expected a write to a missing page to fail")
+	}
+}
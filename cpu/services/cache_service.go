@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -15,16 +16,30 @@ import (
 type PageCache struct {
 	Entries      []models.CacheEntry // Slice de punteros a entradas de caché para permitir nil
 	MaxEntries   int                 // Cantidad máxima de entradas
-	Algorithm    string              // Algoritmo de reemplazo (CLOCK o CLOCK-M)
+	Algorithm    string              // Algoritmo de reemplazo (CLOCK, CLOCK-M, LRU, LFU o 2Q)
+	WritePolicy  string              // Política de escritura (WRITE-BACK, WRITE-THROUGH o WRITE-AROUND)
 	ClockPointer int                 // Puntero para el algoritmo CLOCK/CLOCK-M
-	Mutex        sync.Mutex          // Mutex para control de concurrencia
+	Counter      int64               // Contador de accesos para LRU y 2Q
+	Stats        map[uint]*models.CachePidStats
+	Mutex        sync.Mutex // Mutex para control de concurrencia
 	// Mapa para búsqueda rápida: {PID + PageNumber} -> Índice en Entries
 	PageMap map[struct {
 		PID        uint
 		PageNumber int
 	}]int
+	// 2Q: páginas desalojadas de la cola A1in que todavía se recuerdan (A1out)
+	ghosts []struct {
+		PID        uint
+		PageNumber int
+	}
 }
 
+const (
+	WriteBack   = "WRITE-BACK"
+	WriteThru   = "WRITE-THROUGH"
+	WriteAround = "WRITE-AROUND"
+)
+
 var Cache *PageCache
 
 func InitCache() {
@@ -44,18 +59,36 @@ func SetupCache() *PageCache {
 		maxEntries = 0
 	}
 
+	algorithm := models.CpuConfig.CacheReplacement
+	if !cacheAlgorithms[algorithm] {
+		slog.Warn("Algoritmo de caché desconocido. Se usará CLOCK por defecto.", "algoritmo", algorithm)
+		algorithm = "CLOCK"
+	}
+
+	writePolicy := models.CpuConfig.CacheWritePolicy
+	switch writePolicy {
+	case WriteBack, WriteThru, WriteAround:
+	case "":
+		writePolicy = WriteBack
+	default:
+		slog.Warn("Política de escritura de caché desconocida. Se usará WRITE-BACK por defecto.", "politica", writePolicy)
+		writePolicy = WriteBack
+	}
+
 	cache := &PageCache{
 		Entries:      make([]models.CacheEntry, 0, maxEntries),
 		MaxEntries:   maxEntries,
-		Algorithm:    models.CpuConfig.CacheReplacement,
+		Algorithm:    algorithm,
+		WritePolicy:  writePolicy,
 		ClockPointer: 0,
+		Stats:        make(map[uint]*models.CachePidStats),
 		PageMap: make(map[struct {
 			PID        uint
 			PageNumber int
 		}]int),
 	}
 
-	slog.Debug(fmt.Sprintf("Caché de páginas inicializada. MaxEntries: %d, Algoritmo: %s", cache.MaxEntries, cache.Algorithm))
+	slog.Debug(fmt.Sprintf("Caché de páginas inicializada. MaxEntries: %d, Algoritmo: %s, Escritura: %s", cache.MaxEntries, cache.Algorithm, cache.WritePolicy))
 	return cache
 }
 
@@ -92,12 +125,17 @@ func (cache *PageCache) Get(pid uint, page int) ([]byte, bool) {
 	index, found := cache.PageMap[key]
 	if !found {
 		//Cache MISS
+		cache.statsFor(pid).Misses++
 		slog.Info(fmt.Sprintf("PID: <%d> - Cache Miss - Pagina: <%d>", pid, page))
 		return nil, false
 	}
 
 	//Cache HIT
+	cache.Counter++
 	cache.Entries[index].UseBit = true
+	cache.Entries[index].LastUsed = cache.Counter
+	cache.Entries[index].UseCount++
+	cache.statsFor(pid).Hits++
 	slog.Info(fmt.Sprintf("PID: <%d> - Cache Hit - Pagina: <%d>", pid, page))
 	slog.Debug(fmt.Sprintf("Cache HIT: PID %d, Page %d (slot %d). Content: %s", pid, page, index, cache.Entries[index].Content))
 	return cache.Entries[index].Content, true
@@ -135,20 +173,88 @@ func (cache *PageCache) Put(pid uint, pageNumber int, frameAsignado int, content
 	}
 
 	//Si no existe pero hay espacio libre
-	newCacheEntry := models.CacheEntry{
+	newCacheEntry := cache.newEntry(pid, pageNumber, frameAsignado, content)
+	cache.Entries = append(cache.Entries, newCacheEntry)
+	cache.PageMap[key] = len(cache.Entries) - 1
+	slog.Info(fmt.Sprintf("PID: <%d> - Cache Add - Pagina: <%d>", pid, pageNumber))
+	slog.Debug(fmt.Sprintf("ENTRADAS DE DACHE %v", cache.Entries))
+	slog.Debug(fmt.Sprintf("Cache Add: PID %d, Page %d en nuevo slot %d. Total: %d/%d", pid, pageNumber, len(cache.Entries)-1, len(cache.Entries), cache.MaxEntries))
+}
+
+// Write escribe datos en una página que ya se encuentra en caché y la marca como modificada.
+// Retorna false si la página no está en caché.
+func (cache *PageCache) Write(pid uint, pageNumber int, offset int, data []byte) bool {
+	cache.Mutex.Lock()
+	defer cache.Mutex.Unlock()
+
+	index, found := cache.PageMap[getEntryKey(pid, pageNumber)]
+	if !found {
+		return false
+	}
+
+	entry := &cache.Entries[index]
+	copy(entry.Content[offset:], data)
+	// Con WRITE-THROUGH y WRITE-AROUND la página queda limpia porque el dato se escribe también en Memoria.
+	if cache.WritePolicy == WriteBack {
+		entry.ModifiedBit = true
+	}
+	entry.UseBit = true
+	return true
+}
+
+// WriteThrough escribe en Memoria un dato que se escribió en caché (o que no se trajo a caché con WRITE-AROUND).
+func (cache *PageCache) WriteThrough(pid uint, pageNumber int, physicalAddress int, data []byte) bool {
+	writeReq := models.WriteRequest{
+		Pid:             pid,
+		PhysicalAddress: physicalAddress,
+		Data:            data,
+	}
+	body, err := json.Marshal(writeReq)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error al serializar WriteRequest para PID %d página %d: %v", pid, pageNumber, err))
+		return false
+	}
+
+	_, err = client.DoRequest(models.CpuConfig.PortMemory, models.CpuConfig.IpMemory, "POST", "memoria/write", body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Fallo la escritura en Memoria para PID %d página %d: %v", pid, pageNumber, err))
+		return false
+	}
+
+	cache.Mutex.Lock()
+	cache.statsFor(pid).MemoryUpdates++
+	cache.Mutex.Unlock()
+
+	slog.Info(fmt.Sprintf("PID: <%d> - Memory Update - Página: <%d> - Frame: <%d>", pid, pageNumber, physicalAddress/models.MemConfig.PageSize))
+	return true
+}
+
+// newEntry arma una entrada nueva. Se asume que el Mutex de la caché ya fue adquirido.
+func (cache *PageCache) newEntry(pid uint, pageNumber int, frame int, content []byte) models.CacheEntry {
+	cache.Counter++
+	return models.CacheEntry{
 		PID:         pid,
 		PageNumber:  pageNumber,
 		Content:     content,
-		Frame:       frameAsignado,
+		Frame:       frame,
 		ModifiedBit: false,
 		UseBit:      true,
 		LockerBit:   false,
+		LoadedAt:    cache.Counter,
+		LastUsed:    cache.Counter,
+		UseCount:    1,
+		Hot:         cache.Algorithm == "2Q" && cache.forgetGhost(pid, pageNumber),
 	}
-	cache.Entries = append(cache.Entries, newCacheEntry)
-	cache.PageMap[key] = len(cache.Entries) - 1
-	slog.Info(fmt.Sprintf("PID: <%d> - Cache Add - Pagina: <%d>", pid, pageNumber))
-	slog.Debug(fmt.Sprintf("ENTRADAS DE DACHE %v", cache.Entries))
-	slog.Debug(fmt.Sprintf("Cache Add: PID %d, Page %d en nuevo slot %d. Total: %d/%d", pid, pageNumber, len(cache.Entries)-1, len(cache.Entries), cache.MaxEntries))
+}
+
+// statsFor devuelve los contadores del proceso. Se asume que el Mutex de la caché ya fue adquirido.
+func (cache *PageCache) statsFor(pid uint) *models.CachePidStats {
+	stats, exists := cache.Stats[pid]
+	if !exists {
+		stats = &models.CachePidStats{}
+		cache.Stats[pid] = stats
+	}
+	return stats
 }
 
 // --- CORRECCIÓN CLAVE ---
@@ -161,6 +267,12 @@ func (cache *PageCache) replaceVictim(newPID uint, newPage int, newFrame int, ne
 		victimIndex = cache.findVictimIndexClock()
 	case "CLOCK-M":
 		victimIndex = cache.findVictimIndexClockM()
+	case "LRU":
+		victimIndex = cache.findVictimIndexLRU()
+	case "LFU":
+		victimIndex = cache.findVictimIndexLFU()
+	case "2Q":
+		victimIndex = cache.findVictimIndex2Q()
 	default:
 		victimIndex = cache.ClockPointer
 	}
@@ -168,7 +280,10 @@ func (cache *PageCache) replaceVictim(newPID uint, newPage int, newFrame int, ne
 	victim := cache.Entries[victimIndex]
 	slog.Debug(fmt.Sprintf("Víctima seleccionada (slot %d): PID %d, Page %d (U=%t, M=%t)", victimIndex, victim.PID, victim.PageNumber, victim.UseBit, victim.ModifiedBit))
 
+	victimStats := cache.statsFor(victim.PID)
+	victimStats.Evictions++
 	if victim.ModifiedBit {
+		victimStats.DirtyEvictions++
 		slog.Debug(fmt.Sprintf("Víctima (PID %d, Page %d) modificada. Escribiendo a Memoria Principal.", victim.PID, victim.PageNumber))
 		physicalAddress := victim.Frame * models.MemConfig.PageSize
 
@@ -189,24 +304,20 @@ func (cache *PageCache) replaceVictim(newPID uint, newPage int, newFrame int, ne
 			slog.Error(fmt.Sprintf("Fallo la escritura en Memoria para PID %d página %d: %v", victim.PID, victim.PageNumber, err))
 			return
 		}
+		victimStats.MemoryUpdates++
 		slog.Debug(fmt.Sprintf("Contenido en victim.Content - len: %d - PID: %d - Página: %d", len(victim.Content), victim.PID, victim.PageNumber))
 		slog.Info(fmt.Sprintf("PID: <%d> - Memory Update - Página: <%d> - Frame: <%d>", victim.PID, victim.PageNumber, victim.Frame))
 	}
 
 	// Eliminar de pageMap antes de reemplazar en Entries
 	delete(cache.PageMap, getEntryKey(victim.PID, victim.PageNumber))
+	if cache.Algorithm == "2Q" && !victim.Hot {
+		cache.rememberGhost(victim.PID, victim.PageNumber)
+	}
 
 	// --- CORRECCIÓN CLAVE ---
 	// Se usa el 'newFrame' correcto para la nueva entrada.
-	cache.Entries[victimIndex] = models.CacheEntry{
-		PID:         newPID,
-		PageNumber:  newPage,
-		Content:     newContent,
-		Frame:       newFrame,
-		ModifiedBit: false,
-		UseBit:      true,
-		LockerBit:   false,
-	}
+	cache.Entries[victimIndex] = cache.newEntry(newPID, newPage, newFrame, newContent)
 
 	cache.PageMap[getEntryKey(newPID, newPage)] = victimIndex
 
@@ -349,6 +460,7 @@ func (cache *PageCache) RemoveProcessFromCache(pid uint) {
 				return
 			}
 
+			cache.statsFor(pid).MemoryUpdates++
 			slog.Info(fmt.Sprintf("PID: <%d> - Memory Update - Página: <%d> - Frame: <%d>", pid, entry.PageNumber, entry.Frame))
 		}
 	}
@@ -365,3 +477,46 @@ func (cache *PageCache) RemoveProcessFromCache(pid uint) {
 
 	slog.Debug(fmt.Sprintf("Páginas del Proceso %d desalojadas. Entradas restantes en caché: %d", pid, len(cache.Entries)))
 }
+
+// EntriesByPID devuelve una copia de las páginas del proceso que se encuentran en caché.
+func (cache *PageCache) EntriesByPID(pid uint) []models.DebugCacheEntry {
+	cache.Mutex.Lock()
+	defer cache.Mutex.Unlock()
+
+	entries := make([]models.DebugCacheEntry, 0)
+	for _, entry := range cache.Entries {
+		if entry.PID != pid {
+			continue
+		}
+		entries = append(entries, models.DebugCacheEntry{
+			PageNumber:  entry.PageNumber,
+			Frame:       entry.Frame,
+			Content:     string(bytes.Trim(entry.Content, "\x00")),
+			UseBit:      entry.UseBit,
+			ModifiedBit: entry.ModifiedBit,
+		})
+	}
+	return entries
+}
+
+// Status devuelve la configuración de la caché y los contadores de cada proceso.
+func (cache *PageCache) Status() models.CacheStatus {
+	cache.Mutex.Lock()
+	defer cache.Mutex.Unlock()
+
+	status := models.CacheStatus{
+		Algorithm:   cache.Algorithm,
+		WritePolicy: cache.WritePolicy,
+		MaxEntries:  cache.MaxEntries,
+		Entries:     len(cache.Entries),
+		Stats:       make(map[uint]models.CachePidStats, len(cache.Stats)),
+	}
+	for pid, stats := range cache.Stats {
+		pidStats := *stats
+		if accesses := pidStats.Hits + pidStats.Misses; accesses > 0 {
+			pidStats.HitRatio = float64(pidStats.Hits) / float64(accesses)
+		}
+		status.Stats[pid] = pidStats
+	}
+	return status
+}
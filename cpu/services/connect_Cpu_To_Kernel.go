@@ -4,26 +4,121 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	kernelModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
 )
 
-func ConnectToKernel(idCpu int, cpuConfig *models.Config) {
-	//Crea y codifica la request de conexion a Kernel
+// ConnectToKernel registra cada núcleo de la CPU en el Kernel. El registro es idempotente:
+// el Kernel reconoce los núcleos que ya conoce por su dirección y, por el BootId, si la CPU se reinició.
+func ConnectToKernel(idCpu int, cpuConfig *models.Config) error {
+	for _, core := range Cores {
+		//Crea y codifica la request de conexion a Kernel
+		var request = models.CpuN{Id: idCpu, Core: core.Id, Ip: cpuConfig.IpCpu, Port: cpuConfig.PortCpu, BootId: models.BootId}
+		body, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+
+		//Envia la request de conexion a Kernel
+		response, err := client.DoRequest(cpuConfig.PortKernel, cpuConfig.IpKernel, "POST", "kernel/cpus", body)
+		if response != nil {
+			response.Body.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		slog.Debug(fmt.Sprintf("CPU %d - Núcleo %d registrado en Kernel", idCpu, core.Id))
+	}
+	return nil
+}
+
+// MaintainKernelRegistration registra la CPU reintentando con backoff hasta que el Kernel responda y luego
+// repite el registro cada kernel_heartbeat ms, para volver a registrarse si el Kernel se reinicia.
+func MaintainKernelRegistration(idCpu int, cpuConfig *models.Config, stop <-chan struct{}) {
+	backoff := client.Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second}
+	register := func() error { return ConnectToKernel(idCpu, cpuConfig) }
+
+	if !client.RetryWithBackoff(register, &backoff, stop) {
+		return
+	}
+	slog.Info(fmt.Sprintf("CPU %d registrada en Kernel", idCpu))
+
+	if cpuConfig.KernelHeartbeat <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(cpuConfig.KernelHeartbeat) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !client.RetryWithBackoff(register, &backoff, stop) {
+				return
+			}
+		}
+	}
+}
+
+// NotifyDisconnection avisa al Kernel que la CPU se cierra para que deje de enviarle procesos.
+func NotifyDisconnection(idCpu int, cpuConfig *models.Config) {
 	var request = models.CpuN{Id: idCpu, Ip: cpuConfig.IpCpu, Port: cpuConfig.PortCpu}
 	body, err := json.Marshal(request)
-
 	if err != nil {
 		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+		return
 	}
 
-	//Envia la request de conexion a Kernel
-	_, err = client.DoRequest(cpuConfig.PortKernel, cpuConfig.IpKernel, "POST", "kernel/cpus", body)
+	_, err = client.DoRequest(cpuConfig.PortKernel, cpuConfig.IpKernel, "POST", "kernel/cpu-finalizada", body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("error: %v", err))
+		return
+	}
+	slog.Debug(fmt.Sprintf("CPU %d desconectada del Kernel", idCpu))
+}
 
+// ReportBurstCompletion informa al Kernel el resultado de una ráfaga. Se reintenta con backoff hasta que
+// el Kernel lo acepte o lo rechace, porque sin el informe el proceso queda en EXEC para siempre. El Kernel
+// lo rechaza si el despacho ya no es válido (por ejemplo, si dio de baja a la CPU y recuperó el proceso).
+func ReportBurstCompletion(result kernelModel.PCBExecuteRequest, cpuConfig *models.Config, stop <-chan struct{}) {
+	body, err := json.Marshal(result)
 	if err != nil {
 		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+		return
+	}
+
+	// Los rechazos (4xx) son definitivos: reintentar el mismo informe no cambia la respuesta.
+	rejectedStatus := 0
+	report := func() error {
+		response, err := client.DoRequest(cpuConfig.PortKernel, cpuConfig.IpKernel, "POST", "kernel/resultado-rafaga", body)
+		if response != nil {
+			response.Body.Close()
+		}
+		if err != nil && response != nil && response.StatusCode >= 400 && response.StatusCode < 500 {
+			rejectedStatus = response.StatusCode
+			return nil
+		}
+		return err
+	}
+
+	backoff := client.Backoff{Initial: 200 * time.Millisecond, Max: 5 * time.Second}
+	if !client.RetryWithBackoff(report, &backoff, stop) {
+		slog.Error("La CPU se cierra sin haber informado el resultado de la ráfaga", "pid", result.PID, "despacho", result.DispatchId)
+		return
+	}
+	switch rejectedStatus {
+	case 0:
+	case http.StatusConflict:
+		slog.Warn("El Kernel rechazó el resultado de la ráfaga por ser obsoleto.", "pid", result.PID, "despacho", result.DispatchId)
+		return
+	default:
+		slog.Error("El Kernel rechazó el resultado de la ráfaga", "pid", result.PID, "despacho", result.DispatchId, "status", rejectedStatus)
+		return
 	}
+	slog.Debug(fmt.Sprintf("Resultado de la ráfaga informado - PID: %d - Despacho: %d", result.PID, result.DispatchId))
 }
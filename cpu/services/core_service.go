@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// Core representa un núcleo de la CPU. Cada núcleo tiene sus propios registros,
+// su controlador de interrupciones y su TLB (propia o compartida). La caché de páginas
+// es única y la comparten todos los núcleos.
+type Core struct {
+	Id                int
+	Registers         models.Registers
+	Interrupts        *InterruptController
+	TLB               *TLB
+	InstructionBuffer InstructionBuffer
+	Debugger          *Debugger
+	currentTrace      *models.TraceRecord
+}
+
+var Cores []*Core
+
+// InitCores crea los núcleos indicados en cpu_cores (por defecto uno solo).
+func InitCores() {
+	coreCount := models.CpuConfig.Cores
+	if coreCount <= 0 {
+		coreCount = 1
+	}
+
+	var sharedTLB *TLB
+	if !models.CpuConfig.TlbPerCore {
+		sharedTLB = NewTLB()
+	}
+
+	Cores = make([]*Core, 0, coreCount)
+	for i := 0; i < coreCount; i++ {
+		tlb := sharedTLB
+		if tlb == nil {
+			tlb = NewTLB()
+		}
+		Cores = append(Cores, &Core{
+			Id:         i,
+			Interrupts: NewInterruptController(),
+			TLB:        tlb,
+			Debugger:   NewDebugger(),
+		})
+	}
+
+	slog.Debug(fmt.Sprintf("Núcleos inicializados: %d - TLB por núcleo: %t", coreCount, models.CpuConfig.TlbPerCore))
+}
+
+// GetCore devuelve el núcleo con el id indicado.
+func GetCore(id int) (*Core, bool) {
+	if id < 0 || id >= len(Cores) {
+		return nil, false
+	}
+	return Cores[id], true
+}
+
+// StartExecution prepara el contexto del núcleo para ejecutar el proceso indicado.
+// Devuelve false si el núcleo ya está ejecutando otro proceso.
+func (core *Core) StartExecution(pid uint, pc int) bool {
+	if !core.Interrupts.Start(int(pid)) {
+		return false
+	}
+	core.Registers.PC = uint(pc)
+	core.Interrupts.SetPC(pc)
+	return true
+}
+
+// FinishExecution limpia el contexto del núcleo cuando el proceso lo abandona.
+func (core *Core) FinishExecution() {
+	FlushInstructionBuffer(core)
+	core.Interrupts.Reset(-1)
+	core.Debugger.clearInterrupt()
+}
+
+// RequestInterrupt encola una interrupción si el PID es el que se está ejecutando.
+// Las que devuelven el proceso al Kernel despiertan al núcleo si está pausado por el depurador.
+// Devuelve false si el proceso ya no se encuentra en este núcleo.
+func (core *Core) RequestInterrupt(interrupt models.Interrupt) bool {
+	if !core.Interrupts.Raise(interrupt) {
+		return false
+	}
+	if interrupt.Type != models.InterruptDebug {
+		core.Debugger.Interrupt()
+	}
+	return true
+}
+
+// CheckInterrupt se llama al final de cada ciclo de instrucción. Devuelve la interrupción de mayor
+// prioridad que obliga a devolver el proceso al Kernel. Las de tipo DEBUG solo pausan el núcleo.
+func (core *Core) CheckInterrupt() (models.Interrupt, bool) {
+	for {
+		interrupt, found := core.Interrupts.Next()
+		if !found {
+			return models.Interrupt{}, false
+		}
+		if interrupt.Type != models.InterruptDebug {
+			return interrupt, true
+		}
+		slog.Debug("Interrupción de debug atendida. Se pausa el núcleo.", slog.Int("pid", interrupt.PID), slog.Int("core", core.Id))
+		core.Debugger.Pause()
+	}
+}
+
+// GetCoreStatus devuelve el proceso y el PC de cada núcleo. El Kernel lo usa para reanudar desde ahí
+// a los procesos de una CPU que deja de responder.
+func GetCoreStatus() []models.CoreStatus {
+	result := make([]models.CoreStatus, 0, len(Cores))
+	for _, core := range Cores {
+		pid, pc := core.Interrupts.Progress()
+		result = append(result, models.CoreStatus{Core: core.Id, PID: pid, PC: pc})
+	}
+	return result
+}
+
+// ExecutingPID devuelve el PID en ejecución en el núcleo (-1 si está libre).
+func (core *Core) ExecutingPID() int {
+	return core.Interrupts.ExecutingPID()
+}
+
+// InvalidateProcess escribe en Memoria las páginas modificadas del proceso, las quita de la caché
+// y descarta sus traducciones de la TLB de todos los núcleos. El Kernel no invalida cuando el proceso
+// migra entre núcleos de este módulo, así que cualquiera de ellos puede tener traducciones suyas.
+func InvalidateProcess(pid uint) {
+	if IsEnabled() {
+		Cache.RemoveProcessFromCache(pid)
+	}
+	for _, core := range Cores {
+		if core.TLB.IsEnabled() {
+			core.TLB.RemoveEntriesByPID(pid)
+		}
+	}
+}
@@ -18,9 +18,13 @@ import (
 
 // --- Funciones de Ciclo de Instrucción ---
 
-func Fetch(request memoriaModel.InstructionRequest, cpuConfig *models.Config) memoriaModel.InstructionResponse {
-	query := fmt.Sprintf("memoria/instruccion?pid=%d&pc=%d", request.Pid, request.PC)
+func Fetch(core *Core, request memoriaModel.InstructionRequest, cpuConfig *models.Config) memoriaModel.InstructionResponse {
 	slog.Info(fmt.Sprintf("## PID: <%d> - FETCH - <%d>", request.Pid, request.PC))
+	if IsEnabledInstructionBuffer() {
+		return fetchFromBuffer(core, request, cpuConfig)
+	}
+
+	query := fmt.Sprintf("memoria/instruccion?pid=%d&pc=%d", request.Pid, request.PC)
 	response, err := client.DoRequest(cpuConfig.PortMemory, cpuConfig.IpMemory, "GET", query, nil)
 
 	var instructionResponse memoriaModel.InstructionResponse
@@ -36,7 +40,7 @@ func Fetch(request memoriaModel.InstructionRequest, cpuConfig *models.Config) me
 	return instructionResponse
 }
 
-func DecodeAndExecute(pid uint, instruction string, cpuConfig *models.Config, isFinished *bool, isBlocked *bool, isSyscall *bool, syscallRequest *kernelModel.SyscallRequest) {
+func DecodeAndExecute(core *Core, pid uint, instruction string, cpuConfig *models.Config, isFinished *bool, isBlocked *bool, isSyscall *bool, syscallRequest *kernelModel.SyscallRequest) {
 	parts := strings.Split(instruction, " ")
 	instructionType := parts[0]
 
@@ -47,13 +51,13 @@ func DecodeAndExecute(pid uint, instruction string, cpuConfig *models.Config, is
 
 	switch instructionType {
 	case "NOOP":
-		ExecuteNoop(executeReq)
+		ExecuteNoop(core, executeReq)
 	case "WRITE":
-		ExecuteWrite(executeReq)
+		ExecuteWrite(core, executeReq)
 	case "READ":
-		ExecuteRead(executeReq)
+		ExecuteRead(core, executeReq)
 	case "GOTO":
-		ExecuteGoto(executeReq)
+		ExecuteGoto(core, executeReq)
 	case "INIT_PROC":
 		slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s> - <%s> <%s>", pid, parts[0], parts[1], parts[2]))
 		syncSyscallReq := kernelModel.SyscallRequest{
@@ -66,185 +70,285 @@ func DecodeAndExecute(pid uint, instruction string, cpuConfig *models.Config, is
 		if err != nil {
 			slog.Error("Fallo al ejecutar syscall INIT_PROC", "error", err)
 		}
-		increase_PC()
+		increase_PC(core)
 
 	case "IO", "DUMP_MEMORY":
 		slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s>", pid, instruction))
 		syscallRequest.Pid = pid
 		syscallRequest.Type = instructionType
 		syscallRequest.Values = parts[1:]
-		if IsEnabled() {
-			Cache.RemoveProcessFromCache(pid)
-		}
-		if IsEnabledTLB() {
-			RemoveTLBEntriesByPID(pid)
+		InvalidateProcess(pid)
+		*isBlocked = true
+		*isSyscall = true
+		increase_PC(core)
+
+	case kernelModel.SyscallIoRead, kernelModel.SyscallIoWrite:
+		slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s>", pid, instruction))
+		transfers, ok := translateIoTransfers(core, pid, parts)
+		if !ok {
+			// Como con una dirección inválida en DMA, el proceso no puede seguir: se lo devuelve para finalizarlo.
+			slog.Error(fmt.Sprintf("## PID: <%d> - Instrucción %s con dirección o tamaño inválido. Se finaliza el proceso.", pid, instructionType))
+			InvalidateProcess(pid)
+			*isFinished = true
+			return
 		}
+		syscallRequest.Pid = pid
+		syscallRequest.Type = instructionType
+		syscallRequest.Values = parts[1:]
+		syscallRequest.Transfers = transfers
+		// El dispositivo accede directo a Memoria: se bajan las páginas modificadas y se descarta lo cacheado.
+		InvalidateProcess(pid)
+		*isBlocked = true
+		*isSyscall = true
+		increase_PC(core)
+
+	case kernelModel.SyscallDmaRead, kernelModel.SyscallDmaWrite:
+		slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s>", pid, instruction))
+		syscallRequest.Pid = pid
+		syscallRequest.Type = instructionType
+		syscallRequest.Values = parts[1:]
+		// El Kernel traduce la dirección y mueve los datos con Memoria: se bajan las páginas modificadas y se descarta lo cacheado.
+		InvalidateProcess(pid)
 		*isBlocked = true
 		*isSyscall = true
-		increase_PC()
+		increase_PC(core)
 
 	case "EXIT":
 		slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s>", pid, instruction))
-		if IsEnabled() {
-			Cache.RemoveProcessFromCache(pid)
-		}
-		if IsEnabledTLB() {
-			RemoveTLBEntriesByPID(pid)
-		}
+		InvalidateProcess(pid)
 		*isFinished = true
 
 	default:
 		slog.Error(fmt.Sprintf("Instrucción desconocida: %s", instructionType))
-		increase_PC()
+		increase_PC(core)
 	}
 }
 
 // --- Implementación de Instrucciones ---
 
-func ExecuteNoop(request models.ExecuteInstructionRequest) {
+func ExecuteNoop(core *Core, request models.ExecuteInstructionRequest) {
 	slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s>", request.Pid, request.Values[0]))
-	increase_PC()
+	increase_PC(core)
 }
 
-func ExecuteWrite(request models.ExecuteInstructionRequest) {
+func ExecuteWrite(core *Core, request models.ExecuteInstructionRequest) {
 	slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s> - <%s> <%s>", request.Pid, request.Values[0], request.Values[1], request.Values[2]))
 	logicalAddress, err := strconv.Atoi(request.Values[1])
 	if err != nil {
 		slog.Error("Dirección lógica inválida en WRITE", "error", err)
-		increase_PC()
+		increase_PC(core)
 		return
 	}
 	value := request.Values[2]
-	physicalAddress := TranslateAddress(request.Pid, logicalAddress)
-	if physicalAddress == -1 {
+	data := []byte(value)
+	segments, ok := splitAccess(core, request.Pid, logicalAddress, len(data))
+	if !ok {
 		slog.Warn("Instrucción WRITE no puede continuar: dirección inválida.")
-		increase_PC()
+		increase_PC(core)
 		return
 	}
+	physicalAddress := segments[0].PhysicalAddress
 
-	if IsEnabled() {
-		pageNumber := logicalAddress / models.MemConfig.PageSize
-		offset := logicalAddress % models.MemConfig.PageSize
-		_, found := Cache.Get(request.Pid, pageNumber)
-		if !found {
-			content := getPageFromMemory(request.Pid, pageNumber, physicalAddress, "Escritura")
-			if content == nil {
-				increase_PC()
-				return
+	written := true
+	for _, segment := range segments {
+		segmentData := data[segment.DataOffset : segment.DataOffset+segment.Length]
+		if IsEnabled() {
+			handled, segmentWritten := writeToCache(core, request.Pid, segment, segmentData)
+			if handled {
+				written = written && segmentWritten
+				continue
 			}
-			frame := physicalAddress / models.MemConfig.PageSize
-			Cache.Put(request.Pid, pageNumber, frame, content)
 		}
-		entryKey := getEntryKey(request.Pid, pageNumber)
-		idx := Cache.PageMap[entryKey]
-		entry := &Cache.Entries[idx]
-		copy(entry.Content[offset:], []byte(value))
-		entry.ModifiedBit = true
-		entry.UseBit = true
+		written = writeToMemory(request.Pid, segment.PhysicalAddress, segmentData) && written
+	}
+
+	if written {
 		slog.Info(fmt.Sprintf("## PID: <%d> - ACCIÓN: <ESCRIBIR> - DIRECCIÓN FISICA: <%d> - Valor: <%s>", request.Pid, physicalAddress, value))
-		increase_PC()
-		return
 	}
+	increase_PC(core)
+}
+
+// writeToCache resuelve la escritura de un segmento con la caché habilitada según la política de escritura.
+// handled es false si el dato se debe escribir directamente en Memoria (WRITE-AROUND con la página fuera de caché);
+// written es false si la escritura falló.
+func writeToCache(core *Core, pid uint, segment pageSegment, data []byte) (handled bool, written bool) {
+	_, found := Cache.Get(pid, segment.PageNumber)
+	traceCache(core, found)
+	if !found {
+		if Cache.WritePolicy == WriteAround {
+			slog.Debug("WRITE-AROUND: la página no está en caché, se escribe directamente en Memoria", "pid", pid, "page", segment.PageNumber)
+			return false, false
+		}
+		content := getPageFromMemory(pid, segment.PageNumber, segment.PhysicalAddress, "Escritura")
+		if content == nil {
+			return true, false
+		}
+		frame := segment.PhysicalAddress / models.MemConfig.PageSize
+		Cache.Put(pid, segment.PageNumber, frame, content)
+	}
+	if !Cache.Write(pid, segment.PageNumber, segment.Offset, data) {
+		slog.Error("La página fue desalojada de la caché antes de poder escribirla", "pid", pid, "page", segment.PageNumber)
+		return true, false
+	}
+	if Cache.WritePolicy != WriteBack && !Cache.WriteThrough(pid, segment.PageNumber, segment.PhysicalAddress, data) {
+		return true, false
+	}
+	return true, true
+}
 
+// writeToMemory escribe un segmento directamente en Memoria, sin pasar por la caché.
+func writeToMemory(pid uint, physicalAddress int, data []byte) bool {
 	writeReq := memoriaModel.WriteRequest{
-		Pid:             request.Pid,
+		Pid:             pid,
 		PhysicalAddress: physicalAddress,
-		Data:            []byte(value),
+		Data:            data,
 	}
 	body, _ := json.Marshal(writeReq)
-	_, err = client.DoRequest(models.CpuConfig.PortMemory, models.CpuConfig.IpMemory, "POST", "memoria/write", body)
+	_, err := client.DoRequest(models.CpuConfig.PortMemory, models.CpuConfig.IpMemory, "POST", "memoria/write", body)
 	if err != nil {
 		slog.Error("Fallo la escritura en Memoria", "error", err)
+		return false
 	}
-	slog.Info(fmt.Sprintf("## PID: <%d> - ACCIÓN: <ESCRIBIR> - DIRECCIÓN FISICA: <%d> - Valor: <%s>", request.Pid, physicalAddress, value))
-	increase_PC()
+	return true
 }
 
-func ExecuteRead(request models.ExecuteInstructionRequest) {
+func ExecuteRead(core *Core, request models.ExecuteInstructionRequest) {
 	slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s> - <%s> <%s>", request.Pid, request.Values[0], request.Values[1], request.Values[2]))
 	logicalAddress, err := strconv.Atoi(request.Values[1])
 	if err != nil {
 		slog.Error("Dirección lógica inválida en READ", "error", err)
-		increase_PC()
+		increase_PC(core)
 		return
 	}
 	size, err := strconv.Atoi(request.Values[2])
-	if err != nil {
-		slog.Error("Tamaño inválido en READ", "error", err)
-		increase_PC()
+	if err != nil || size < 0 {
+		slog.Error("Tamaño inválido en READ", "error", err, "size", size)
+		increase_PC(core)
 		return
 	}
 
-	physicalAddress := TranslateAddress(request.Pid, logicalAddress)
-	if physicalAddress == -1 {
+	segments, ok := splitAccess(core, request.Pid, logicalAddress, size)
+	if !ok {
 		slog.Warn("Instrucción READ no puede continuar: dirección inválida.")
-		increase_PC()
+		increase_PC(core)
 		return
 	}
+	physicalAddress := segments[0].PhysicalAddress
 
-	if IsEnabled() {
-		pageNumber := logicalAddress / models.MemConfig.PageSize
-		offset := logicalAddress % models.MemConfig.PageSize
-		content, found := Cache.Get(request.Pid, pageNumber)
-		if !found {
-			content = getPageFromMemory(request.Pid, pageNumber, physicalAddress, "Lectura")
-			if content == nil {
-				increase_PC()
-				return
-			}
-			frame := physicalAddress / models.MemConfig.PageSize
-			Cache.Put(request.Pid, pageNumber, frame, content)
+	data := make([]byte, 0, size)
+	for _, segment := range segments {
+		var segmentData []byte
+		if IsEnabled() {
+			segmentData = readFromCache(core, request.Pid, segment)
+		} else {
+			segmentData = readFromMemory(request.Pid, segment.PhysicalAddress, segment.Length)
+		}
+		if segmentData == nil {
+			increase_PC(core)
+			return
 		}
-		data := content[offset : offset+size]
-		cleanData := bytes.Trim(data, "\x00")
+		data = append(data, segmentData...)
+	}
+
+	cleanData := bytes.Trim(data, "\x00")
+	if IsEnabled() {
 		slog.Info(fmt.Sprintf("## PID: <%d> - ACCIÓN: <LEER> - DIRECCIÓN FISICA: <%d> - Valor: <%s>", request.Pid, physicalAddress, string(cleanData)))
-		increase_PC()
-		return
+	} else {
+		slog.Info(fmt.Sprintf("## PID: %d - ACCIÓN: LEER - DIRECCIÓN FISICA: %d - Valor: %s", request.Pid, physicalAddress, string(cleanData)))
+	}
+	increase_PC(core)
+}
+
+// readFromCache lee un segmento desde la caché, trayendo la página de Memoria si no está.
+func readFromCache(core *Core, pid uint, segment pageSegment) []byte {
+	content, found := Cache.Get(pid, segment.PageNumber)
+	traceCache(core, found)
+	if !found {
+		content = getPageFromMemory(pid, segment.PageNumber, segment.PhysicalAddress, "Lectura")
+		if content == nil {
+			return nil
+		}
+		frame := segment.PhysicalAddress / models.MemConfig.PageSize
+		Cache.Put(pid, segment.PageNumber, frame, content)
+	}
+	if segment.Offset+segment.Length > len(content) {
+		slog.Error("La página obtenida es más chica que el segmento a leer", "pid", pid, "page", segment.PageNumber, "len", len(content))
+		return nil
 	}
+	return content[segment.Offset : segment.Offset+segment.Length]
+}
 
+// readFromMemory lee un segmento directamente de Memoria, sin pasar por la caché.
+func readFromMemory(pid uint, physicalAddress int, size int) []byte {
 	readRequest := memoriaModel.ReadRequest{
-		Pid:             request.Pid,
+		Pid:             pid,
 		PhysicalAddress: physicalAddress,
 		Size:            size,
 	}
 	jsonBody, _ := json.Marshal(readRequest)
 	response, err := client.DoRequest(models.CpuConfig.PortMemory, models.CpuConfig.IpMemory, "POST", "memoria/leerMemoria", jsonBody)
 	if err != nil {
-		increase_PC()
-		return
+		return nil
 	}
 	defer response.Body.Close()
 	if response.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(response.Body)
 		slog.Error("Error al leer desde Memoria", "status", response.StatusCode, "body", string(body))
-		increase_PC()
-		return
+		return nil
 	}
 
 	var memoryResponse struct {
 		Content []byte `json:"content"`
 	}
-	json.NewDecoder(response.Body).Decode(&memoryResponse)
-	cleanData := bytes.Trim(memoryResponse.Content, "\x00")
-	slog.Info(fmt.Sprintf("## PID: %d - ACCIÓN: LEER - DIRECCIÓN FISICA: %d - Valor: %s", request.Pid, physicalAddress, string(cleanData)))
-	increase_PC()
+	if err := json.NewDecoder(response.Body).Decode(&memoryResponse); err != nil {
+		slog.Error("Error decodificando lectura de Memoria", "error", err)
+		return nil
+	}
+	return memoryResponse.Content
 }
 
-func ExecuteGoto(request models.ExecuteInstructionRequest) {
+func ExecuteGoto(core *Core, request models.ExecuteInstructionRequest) {
 	slog.Info(fmt.Sprintf("## PID: <%d> - Ejecutando: <%s> - <%s>", request.Pid, request.Values[0], request.Values[1]))
 	value, _ := strconv.Atoi(request.Values[1])
 	if value > 0 {
-		models.CpuRegisters.PC = uint(value - 1)
+		core.Registers.PC = uint(value - 1)
 	} else {
-		models.CpuRegisters.PC = 0
+		core.Registers.PC = 0
 	}
+	InvalidateBufferOnJump(core, request.Pid, int(core.Registers.PC))
 }
 
 // --- Funciones Auxiliares ---
 
-func increase_PC() {
-	models.CpuRegisters.PC++
-	slog.Debug(fmt.Sprintf("Valor actual de PC: %d", models.CpuRegisters.PC))
+// translateIoTransfers traduce la dirección lógica de IO_READ e IO_WRITE (<disp> <dir> <tam>) a los tramos
+// de memoria física que el dispositivo debe leer o escribir, uno por página.
+func translateIoTransfers(core *Core, pid uint, parts []string) ([]kernelModel.IoTransfer, bool) {
+	if len(parts) < 4 {
+		return nil, false
+	}
+	logicalAddress, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, false
+	}
+	size, err := strconv.Atoi(parts[3])
+	if err != nil || size <= 0 {
+		return nil, false
+	}
+
+	segments, ok := splitAccess(core, pid, logicalAddress, size)
+	if !ok {
+		return nil, false
+	}
+	transfers := make([]kernelModel.IoTransfer, 0, len(segments))
+	for _, segment := range segments {
+		transfers = append(transfers, kernelModel.IoTransfer{PhysicalAddress: segment.PhysicalAddress, Length: segment.Length})
+	}
+	return transfers, true
+}
+
+func increase_PC(core *Core) {
+	core.Registers.PC++
+	slog.Debug(fmt.Sprintf("Núcleo %d - Valor actual de PC: %d", core.Id, core.Registers.PC))
 }
 
 func getPageFromMemory(pid uint, pageNumber int, physicalAddress int, operacion string) []byte {
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+var ErrCoreNotPaused = errors.New("el núcleo no está pausado")
+
+// Los breakpoints son compartidos por todos los núcleos: un proceso se detiene en el PC
+// indicado sin importar en qué núcleo se esté ejecutando.
+var (
+	breakpoints      = make(map[models.Breakpoint]bool)
+	breakpointsMutex sync.RWMutex
+)
+
+// Debugger controla la ejecución de un núcleo. Se consulta entre el Fetch y el Decode.
+type Debugger struct {
+	mutex          sync.Mutex
+	pauseRequested bool
+	paused         bool
+	interrupted    bool // Llegó una interrupción que devuelve el proceso al Kernel: no se pausa antes de atenderla
+	pc             int  // Último PC consultado por el núcleo, para leerlo sin tocar sus registros
+	instruction    string
+	resume         chan bool     // true = ejecutar una sola instrucción, false = continuar
+	wake           chan struct{} // Despierta una pausa para atender una interrupción
+}
+
+func NewDebugger() *Debugger {
+	return &Debugger{resume: make(chan bool), wake: make(chan struct{}, 1)}
+}
+
+func AddBreakpoint(breakpoint models.Breakpoint) {
+	breakpointsMutex.Lock()
+	defer breakpointsMutex.Unlock()
+	breakpoints[breakpoint] = true
+	slog.Debug(fmt.Sprintf("Breakpoint agregado - PID: %d - PC: %d", breakpoint.PID, breakpoint.PC))
+}
+
+func RemoveBreakpoint(breakpoint models.Breakpoint) {
+	breakpointsMutex.Lock()
+	defer breakpointsMutex.Unlock()
+	delete(breakpoints, breakpoint)
+	slog.Debug(fmt.Sprintf("Breakpoint eliminado - PID: %d - PC: %d", breakpoint.PID, breakpoint.PC))
+}
+
+func GetBreakpoints() []models.Breakpoint {
+	breakpointsMutex.RLock()
+	defer breakpointsMutex.RUnlock()
+	result := make([]models.Breakpoint, 0, len(breakpoints))
+	for breakpoint := range breakpoints {
+		result = append(result, breakpoint)
+	}
+	return result
+}
+
+func hasBreakpoint(pid uint, pc int) bool {
+	breakpointsMutex.RLock()
+	defer breakpointsMutex.RUnlock()
+	return breakpoints[models.Breakpoint{PID: pid, PC: pc}]
+}
+
+// BeforeExecute bloquea al núcleo si hay un breakpoint en (PID, PC) o se pidió una pausa,
+// hasta que se ordene continuar o avanzar un paso. Devuelve false si una interrupción despertó la pausa
+// (o llegó antes): la instrucción no se ejecuta y el núcleo debe atender la interrupción.
+func (debugger *Debugger) BeforeExecute(pid uint, pc int, instruction string) bool {
+	debugger.mutex.Lock()
+	debugger.pc = pc
+	if debugger.interrupted {
+		debugger.interrupted = false
+		debugger.mutex.Unlock()
+		return false
+	}
+	if !debugger.pauseRequested && !hasBreakpoint(pid, pc) {
+		debugger.mutex.Unlock()
+		return true
+	}
+	debugger.paused = true
+	debugger.pauseRequested = false
+	debugger.instruction = instruction
+	debugger.mutex.Unlock()
+
+	slog.Info(fmt.Sprintf("## PID: <%d> - Ejecución pausada - PC: <%d> - Instrucción: <%s>", pid, pc, instruction))
+	select {
+	case step := <-debugger.resume:
+		debugger.mutex.Lock()
+		debugger.pauseRequested = step
+		debugger.mutex.Unlock()
+		return true
+	case <-debugger.wake:
+		slog.Info(fmt.Sprintf("## PID: <%d> - Pausa interrumpida - PC: <%d>", pid, pc))
+		return false
+	}
+}
+
+// Interrupt avisa que hay una interrupción que devuelve el proceso al Kernel (KILL, SUSPEND, etc.).
+// Si el núcleo está pausado lo despierta sin ejecutar la instrucción; si no, evita que se pause antes de atenderla.
+func (debugger *Debugger) Interrupt() {
+	debugger.mutex.Lock()
+	defer debugger.mutex.Unlock()
+	if !debugger.paused {
+		debugger.interrupted = true
+		return
+	}
+	debugger.paused = false
+	debugger.wake <- struct{}{}
+}
+
+// clearInterrupt descarta el aviso de interrupción cuando el proceso deja el núcleo.
+func (debugger *Debugger) clearInterrupt() {
+	debugger.mutex.Lock()
+	defer debugger.mutex.Unlock()
+	debugger.interrupted = false
+}
+
+// Pause pide detener el núcleo antes de la próxima instrucción.
+func (debugger *Debugger) Pause() {
+	debugger.mutex.Lock()
+	defer debugger.mutex.Unlock()
+	debugger.pauseRequested = true
+}
+
+// Step ejecuta una única instrucción y vuelve a pausar el núcleo.
+func (debugger *Debugger) Step() error {
+	return debugger.release(true)
+}
+
+// Continue reanuda la ejecución hasta el próximo breakpoint o pausa.
+func (debugger *Debugger) Continue() error {
+	debugger.mutex.Lock()
+	debugger.pauseRequested = false
+	debugger.mutex.Unlock()
+	return debugger.release(false)
+}
+
+// release reanuda el núcleo pausado. paused se limpia bajo el mutex antes de enviar, así de dos
+// Step o Continue simultáneos (o de una interrupción) solo uno despierta al núcleo y el resto recibe ErrCoreNotPaused.
+func (debugger *Debugger) release(step bool) error {
+	debugger.mutex.Lock()
+	if !debugger.paused {
+		debugger.mutex.Unlock()
+		return ErrCoreNotPaused
+	}
+	debugger.paused = false
+	debugger.mutex.Unlock()
+	debugger.resume <- step
+	return nil
+}
+
+// GetDebugState arma una foto del núcleo: registros, instrucción pausada, TLB y caché del PID en ejecución.
+func GetDebugState(core *Core) models.DebugState {
+	pid := core.ExecutingPID()
+
+	core.Debugger.mutex.Lock()
+	state := models.DebugState{
+		Core:        core.Id,
+		PID:         pid,
+		PC:          uint(core.Debugger.pc),
+		Paused:      core.Debugger.paused,
+		Instruction: core.Debugger.instruction,
+	}
+	core.Debugger.mutex.Unlock()
+
+	if pid < 0 {
+		return state
+	}
+	state.TLB = core.TLB.EntriesByPID(uint(pid))
+	if IsEnabled() {
+		state.Cache = Cache.EntriesByPID(uint(pid))
+	}
+	return state
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	memoriaModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/memoria/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
+)
+
+// InstructionBuffer guarda una ventana de instrucciones pedida a Memoria en un único request.
+// Consumed cuenta las instrucciones servidas desde el buffer que todavía no se informaron a Memoria.
+type InstructionBuffer struct {
+	PID          uint
+	StartPC      int
+	Instructions []string
+	Total        int
+	Consumed     int
+}
+
+// IsEnabledInstructionBuffer verifica si el fetch por ventanas está habilitado.
+func IsEnabledInstructionBuffer() bool {
+	return models.CpuConfig.InstructionBufferSize > 0
+}
+
+// contains indica si la instrucción pedida se encuentra en la ventana actual.
+func (buffer *InstructionBuffer) contains(pid uint, pc int) bool {
+	return buffer.PID == pid && len(buffer.Instructions) > 0 && pc >= buffer.StartPC && pc < buffer.StartPC+len(buffer.Instructions)
+}
+
+// invalidate descarta la ventana actual. Las instrucciones consumidas se conservan hasta que se informen.
+func (buffer *InstructionBuffer) invalidate() {
+	buffer.StartPC = 0
+	buffer.Instructions = nil
+	buffer.Total = 0
+}
+
+// fetchFromBuffer devuelve la instrucción desde el buffer del núcleo, pidiendo una nueva ventana si hace falta.
+func fetchFromBuffer(core *Core, request memoriaModel.InstructionRequest, cpuConfig *models.Config) memoriaModel.InstructionResponse {
+	buffer := &core.InstructionBuffer
+	if buffer.PID != request.Pid {
+		FlushInstructionBuffer(core)
+		buffer.PID = request.Pid
+	}
+
+	if !buffer.contains(request.Pid, request.PC) {
+		if !refillInstructionBuffer(buffer, request, cpuConfig) {
+			return memoriaModel.InstructionResponse{}
+		}
+	}
+
+	index := request.PC - buffer.StartPC
+	buffer.Consumed++
+	instruction := buffer.Instructions[index]
+	slog.Debug(fmt.Sprintf("Instrucción obtenida del buffer: %s", instruction))
+	return memoriaModel.InstructionResponse{
+		Instruction: instruction,
+		IsLast:      request.PC == buffer.Total-1,
+	}
+}
+
+func refillInstructionBuffer(buffer *InstructionBuffer, request memoriaModel.InstructionRequest, cpuConfig *models.Config) bool {
+	query := fmt.Sprintf("memoria/instrucciones?pid=%d&pc=%d&cantidad=%d", request.Pid, request.PC, cpuConfig.InstructionBufferSize)
+	response, err := client.DoRequest(cpuConfig.PortMemory, cpuConfig.IpMemory, "GET", query, nil)
+	if err != nil {
+		slog.Error("Error al pedir ventana de instrucciones a Memoria", "error", err)
+		buffer.invalidate()
+		return false
+	}
+	defer response.Body.Close()
+
+	var window memoriaModel.InstructionsWindowResponse
+	if err := json.NewDecoder(response.Body).Decode(&window); err != nil || len(window.Instructions) == 0 {
+		slog.Error("Error decodificando ventana de instrucciones", "error", err)
+		buffer.invalidate()
+		return false
+	}
+
+	buffer.StartPC = window.PC
+	buffer.Instructions = window.Instructions
+	buffer.Total = window.Total
+	slog.Debug(fmt.Sprintf("Buffer de instrucciones cargado - PID: %d - PC: %d a %d", request.Pid, window.PC, window.PC+len(window.Instructions)-1))
+	return true
+}
+
+// InvalidateBufferOnJump descarta la ventana si el salto de un GOTO cae fuera de ella.
+func InvalidateBufferOnJump(core *Core, pid uint, targetPC int) {
+	if !IsEnabledInstructionBuffer() {
+		return
+	}
+	if !core.InstructionBuffer.contains(pid, targetPC) {
+		slog.Debug("GOTO fuera de la ventana de instrucciones. Se invalida el buffer.", "pid", pid, "pc", targetPC)
+		core.InstructionBuffer.invalidate()
+	}
+}
+
+// FlushInstructionBuffer informa a Memoria las instrucciones consumidas y vacía el buffer.
+// Se llama en cada cambio de contexto para que las métricas de fetch queden al día.
+func FlushInstructionBuffer(core *Core) {
+	buffer := &core.InstructionBuffer
+	if buffer.Consumed > 0 {
+		reportInstructionFetches(buffer.PID, buffer.Consumed)
+	}
+	*buffer = InstructionBuffer{}
+}
+
+func reportInstructionFetches(pid uint, count int) {
+	request := memoriaModel.InstructionFetchesRequest{Pid: pid, Count: count}
+	body, err := json.Marshal(request)
+	if err != nil {
+		slog.Error("Error serializando fetches consumidos", "error", err)
+		return
+	}
+
+	_, err = client.DoRequest(models.CpuConfig.PortMemory, models.CpuConfig.IpMemory, "POST", "memoria/instrucciones/consumidas", body)
+	if err != nil {
+		slog.Error("Error informando fetches consumidos a Memoria", "pid", pid, "error", err)
+	}
+}
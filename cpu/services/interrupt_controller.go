@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// InterruptController encola las interrupciones recibidas para el proceso en ejecución en un núcleo.
+// Se consultan al final de cada ciclo de instrucción, de mayor a menor prioridad.
+type InterruptController struct {
+	mutex   sync.Mutex
+	pid     int
+	pc      int // Próxima instrucción del proceso en ejecución, para informarla en el chequeo de salud.
+	pending []models.Interrupt
+}
+
+func NewInterruptController() *InterruptController {
+	return &InterruptController{pid: -1}
+}
+
+// IsValidInterruptType indica si el tipo de interrupción es uno de los conocidos.
+func IsValidInterruptType(interruptType models.InterruptType) bool {
+	_, exists := models.InterruptPriority[interruptType]
+	return exists
+}
+
+// Reset asigna el PID en ejecución y descarta las interrupciones pendientes del proceso anterior.
+func (controller *InterruptController) Reset(pid int) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	if len(controller.pending) > 0 {
+		slog.Debug(fmt.Sprintf("Se descartan %d interrupciones pendientes del PID %d", len(controller.pending), controller.pid))
+	}
+	controller.pid = pid
+	controller.pending = nil
+}
+
+// Start asigna el PID en ejecución si el núcleo está libre. Devuelve false si ya hay un proceso ejecutando.
+func (controller *InterruptController) Start(pid int) bool {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	if controller.pid != -1 {
+		return false
+	}
+	controller.pid = pid
+	controller.pending = nil
+	return true
+}
+
+// Raise encola la interrupción si el PID es el que se está ejecutando.
+// Devuelve false si el proceso ya no se encuentra en el núcleo.
+func (controller *InterruptController) Raise(interrupt models.Interrupt) bool {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	if interrupt.PID != controller.pid {
+		return false
+	}
+	for _, pending := range controller.pending {
+		if pending.Type == interrupt.Type {
+			return true
+		}
+	}
+
+	controller.pending = append(controller.pending, interrupt)
+	sort.SliceStable(controller.pending, func(i, j int) bool {
+		return models.InterruptPriority[controller.pending[i].Type] > models.InterruptPriority[controller.pending[j].Type]
+	})
+	return true
+}
+
+// Next saca de la cola la interrupción pendiente de mayor prioridad.
+func (controller *InterruptController) Next() (models.Interrupt, bool) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	if len(controller.pending) == 0 {
+		return models.Interrupt{}, false
+	}
+	interrupt := controller.pending[0]
+	controller.pending = controller.pending[1:]
+	return interrupt, true
+}
+
+// SetPC registra la próxima instrucción del proceso en ejecución.
+func (controller *InterruptController) SetPC(pc int) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	controller.pc = pc
+}
+
+// Progress devuelve el PID en ejecución (-1 si el núcleo está libre) y su próxima instrucción.
+func (controller *InterruptController) Progress() (int, int) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.pid, controller.pc
+}
+
+// ExecutingPID devuelve el PID en ejecución (-1 si el núcleo está libre).
+func (controller *InterruptController) ExecutingPID() int {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	return controller.pid
+}
@@ -10,21 +10,43 @@ import (
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
 )
 
-var (
-	tlb            []models.TLBEntry
-	tlbMaxSize     int
-	tlbAlgorithm   string // "FIFO" o "LRU"
-	tlbCounter     int64  // para LRU, contador incremental
-	tlbFifoPointer int    // **NUEVO**: Puntero para FIFO
-	tlbMutex       sync.Mutex
-)
+// TLB representa una TLB. Puede ser propia de un núcleo o compartida entre todos (tlb_per_core).
+type TLB struct {
+	Entries      []models.TLBEntry
+	MaxSize      int
+	Algorithm    string // "FIFO", "LRU", "LFU", "CLOCK", "RANDOM" u "OPT"
+	Counter      int64  // para LRU, contador incremental
+	FifoPointer  int    // Puntero para FIFO
+	ClockPointer int    // Puntero para CLOCK
+	Stats        map[uint]*models.TLBPidStats
+	Mutex        sync.Mutex // Mutex para control de concurrencia
+
+	references        []tlbReference // accesos futuros para OPT
+	referencePosition int
+}
 
-func InitTLB() {
-	tlbMaxSize = models.CpuConfig.TlbEntries
-	tlbAlgorithm = models.CpuConfig.TlbReplacement // "FIFO" o "LRU"
-	tlbCounter = 0
-	tlbFifoPointer = 0 // **NUEVO**: Inicializamos el puntero
-	tlb = make([]models.TLBEntry, 0, tlbMaxSize)
+// NewTLB crea una TLB vacía a partir de la configuración de la CPU.
+func NewTLB() *TLB {
+	maxSize := models.CpuConfig.TlbEntries
+	if maxSize < 0 {
+		maxSize = 0
+	}
+	algorithm := models.CpuConfig.TlbReplacement
+	if !tlbAlgorithms[algorithm] {
+		slog.Warn("Algoritmo TLB desconocido. Se usará FIFO por defecto.", "algoritmo", algorithm)
+		algorithm = "FIFO"
+	}
+
+	tlb := &TLB{
+		Entries:     make([]models.TLBEntry, 0, maxSize),
+		MaxSize:     maxSize,
+		Algorithm:   algorithm,
+		Counter:     0,
+		FifoPointer: 0,
+		Stats:       make(map[uint]*models.TLBPidStats),
+	}
+	tlb.loadOptimalTrace()
+	return tlb
 }
 
 func RequestMemoryConfig() error {
@@ -45,11 +67,13 @@ func RequestMemoryConfig() error {
 	return nil
 }
 
-func IsEnabledTLB() bool {
-	return tlbMaxSize > 0
+// IsEnabled verifica si la TLB está habilitada.
+func (tlb *TLB) IsEnabled() bool {
+	return tlb.MaxSize > 0
 }
 
-func TranslateAddress(pid uint, logicalAddress int) int {
+// TranslateAddress traduce una dirección lógica usando la TLB del núcleo que ejecuta.
+func TranslateAddress(core *Core, pid uint, logicalAddress int) int {
 	pageSize := models.MemConfig.PageSize
 	pageNumber := logicalAddress / pageSize
 	offset := logicalAddress % pageSize
@@ -57,21 +81,24 @@ func TranslateAddress(pid uint, logicalAddress int) int {
 	slog.Debug("Traducción de dirección", "pid", pid, "logical", logicalAddress, "pageNumber", pageNumber, "pageSize", pageSize)
 
 	//Verifica que la tlb no este desactivada
-	if IsEnabledTLB() {
-		if frame, ok := searchTLB(pid, pageNumber); ok {
+	if core.TLB.IsEnabled() {
+		if frame, ok := core.TLB.search(pid, pageNumber); ok {
 			//si la encuentra imprime TLB HIT y traduce
 			slog.Info(fmt.Sprintf("PID: <%d> - TLB HIT - Pagina: <%d>", pid, pageNumber))
 			slog.Info(fmt.Sprintf("PID: <%d> - OBTENER MARCO - Página: <%d> - Marco: <%d>", pid, pageNumber, frame))
+			traceTranslation(core, logicalAddress, frame*pageSize+offset, "HIT")
 			return frame*pageSize + offset
 		}
 		slog.Info(fmt.Sprintf("PID: <%d> - TLB MISS - Página: <%d>", pid, pageNumber))
 		frame := tlb_miss(pid, pageNumber)
 		if frame == -1 {
 			slog.Warn("Violación de memoria detectada (TLB MISS)", "pid", pid, "page", pageNumber)
+			traceTranslation(core, logicalAddress, -1, "MISS")
 			return -1
 		}
-		insert_tlb(pid, pageNumber, frame)
+		core.TLB.insert(pid, pageNumber, frame)
 		slog.Info(fmt.Sprintf("PID: <%d> - OBTENER MARCO - Página: <%d> - Marco: <%d>", pid, pageNumber, frame))
+		traceTranslation(core, logicalAddress, frame*pageSize+offset, "MISS")
 		return frame*pageSize + offset
 	}
 	// TLB desactivada
@@ -79,10 +106,12 @@ func TranslateAddress(pid uint, logicalAddress int) int {
 	frame := tlb_miss(pid, pageNumber)
 	if frame == -1 {
 		slog.Warn("Violación de memoria detectada (TLB MISS)", "pid", pid, "page", pageNumber)
+		traceTranslation(core, logicalAddress, -1, "DISABLED")
 		return -1
 	}
 	//slog.Debug("RequestMemoryFrame", "pid", pid, "frame", frame)
 	slog.Info(fmt.Sprintf("PID: <%d> - OBTENER MARCO - Página: <%d> - Marco: <%d>", pid, pageNumber, frame))
+	traceTranslation(core, logicalAddress, frame*pageSize+offset, "DISABLED")
 	return frame*pageSize + offset
 
 }
@@ -92,62 +121,61 @@ func tlb_miss(pid uint, pageNumber int) int {
 	return RequestMemoryFrame(pid, pageNumber)
 }
 
-func searchTLB(pid uint, pagina int) (int, bool) {
-	tlbMutex.Lock()
-	defer tlbMutex.Unlock()
-
-	for i := range tlb {
-		if tlb[i].PID == pid && tlb[i].PageNumber == pagina {
-			if tlbAlgorithm == "LRU" {
-				tlbCounter++
-				tlb[i].LastUsed = tlbCounter
-			}
-			return tlb[i].FrameNumber, true
+func (tlb *TLB) search(pid uint, pagina int) (int, bool) {
+	tlb.Mutex.Lock()
+	defer tlb.Mutex.Unlock()
+
+	tlb.advanceReferences(pid, pagina)
+	for i := range tlb.Entries {
+		if tlb.Entries[i].PID == pid && tlb.Entries[i].PageNumber == pagina {
+			tlb.Counter++
+			tlb.Entries[i].LastUsed = tlb.Counter
+			tlb.Entries[i].UseCount++
+			tlb.Entries[i].Referenced = true
+			tlb.statsFor(pid).Hits++
+			return tlb.Entries[i].FrameNumber, true
 		}
 	}
+	tlb.statsFor(pid).Misses++
 	return 0, false
 }
 
-func insert_tlb(pid uint, pagina int, frame int) {
-	tlbMutex.Lock()
-	defer tlbMutex.Unlock()
+func (tlb *TLB) insert(pid uint, pagina int, frame int) {
+	tlb.Mutex.Lock()
+	defer tlb.Mutex.Unlock()
 
-	tlbCounter++
+	tlb.Counter++
 	entry := models.TLBEntry{
 		PID:         pid,
 		PageNumber:  pagina,
 		FrameNumber: frame,
-		LastUsed:    tlbCounter,
+		LastUsed:    tlb.Counter,
+		UseCount:    1,
+		Referenced:  true,
 	}
 
-	if len(tlb) < tlbMaxSize {
-		tlb = append(tlb, entry)
+	if len(tlb.Entries) < tlb.MaxSize {
+		tlb.Entries = append(tlb.Entries, entry)
 		return
 	}
 
-	var victimIndex int
-	switch tlbAlgorithm {
-	case "FIFO":
-		victimIndex = tlbFifoPointer
-		tlbFifoPointer = (tlbFifoPointer + 1) % tlbMaxSize // Avanza el puntero circularmente
-	case "LRU":
-		minUsage := tlb[0].LastUsed
-		victimIndex = 0
-		for i, e := range tlb {
-			if e.LastUsed < minUsage {
-				minUsage = e.LastUsed
-				victimIndex = i
-			}
-		}
-	default:
-		slog.Warn("Algoritmo TLB desconocido. Se usará FIFO por defecto.")
-		victimIndex = 0
-	}
+	victimIndex := tlb.selectVictim()
 
-	tlb[victimIndex] = entry
-	slog.Debug(fmt.Sprintf("TLB reemplazo: Reemplazando entrada PID %d - Página %d por PID %d - Página %d",
-		tlb[victimIndex].PID, tlb[victimIndex].PageNumber,
+	slog.Debug(fmt.Sprintf("TLB reemplazo (%s): Reemplazando entrada PID %d - Página %d por PID %d - Página %d",
+		tlb.Algorithm, tlb.Entries[victimIndex].PID, tlb.Entries[victimIndex].PageNumber,
 		entry.PID, entry.PageNumber))
+	tlb.statsFor(tlb.Entries[victimIndex].PID).Evictions++
+	tlb.Entries[victimIndex] = entry
+}
+
+// statsFor devuelve los contadores del proceso. Se asume que el Mutex de la TLB ya fue adquirido.
+func (tlb *TLB) statsFor(pid uint) *models.TLBPidStats {
+	stats, exists := tlb.Stats[pid]
+	if !exists {
+		stats = &models.TLBPidStats{}
+		tlb.Stats[pid] = stats
+	}
+	return stats
 }
 
 func RequestMemoryFrame(pid uint, pageNumber int) int {
@@ -183,16 +211,65 @@ func RequestMemoryFrame(pid uint, pageNumber int) int {
 	return decoded.Frame
 }
 
-// Elimina las TLBs de los procesos que sean finalizados.
-func RemoveTLBEntriesByPID(pid uint) {
-	tlbMutex.Lock()
-	defer tlbMutex.Unlock()
+// RemoveEntriesByPID elimina las entradas de la TLB de los procesos que sean finalizados.
+func (tlb *TLB) RemoveEntriesByPID(pid uint) {
+	tlb.Mutex.Lock()
+	defer tlb.Mutex.Unlock()
 
-	filtered := make([]models.TLBEntry, 0, len(tlb))
-	for _, entry := range tlb {
+	filtered := make([]models.TLBEntry, 0, len(tlb.Entries))
+	for _, entry := range tlb.Entries {
 		if entry.PID != pid {
 			filtered = append(filtered, entry)
 		}
 	}
-	tlb = filtered
+	tlb.Entries = filtered
+}
+
+// EntriesByPID devuelve una copia de las entradas de la TLB que pertenecen al proceso.
+func (tlb *TLB) EntriesByPID(pid uint) []models.TLBEntry {
+	tlb.Mutex.Lock()
+	defer tlb.Mutex.Unlock()
+
+	entries := make([]models.TLBEntry, 0)
+	for _, entry := range tlb.Entries {
+		if entry.PID == pid {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Status devuelve una copia de las entradas y los contadores de la TLB.
+func (tlb *TLB) Status() models.TLBStatus {
+	tlb.Mutex.Lock()
+	defer tlb.Mutex.Unlock()
+
+	status := models.TLBStatus{
+		Cores:     make([]int, 0),
+		Algorithm: tlb.Algorithm,
+		MaxSize:   tlb.MaxSize,
+		Entries:   append([]models.TLBEntry{}, tlb.Entries...),
+		Stats:     make(map[uint]models.TLBPidStats, len(tlb.Stats)),
+	}
+	for pid, stats := range tlb.Stats {
+		status.Stats[pid] = *stats
+	}
+	return status
+}
+
+// GetTLBStatus devuelve el estado de cada TLB de la CPU. Si la TLB es compartida se informa una sola vez
+// junto con todos los núcleos que la usan.
+func GetTLBStatus() []models.TLBStatus {
+	result := make([]models.TLBStatus, 0)
+	indexByTLB := make(map[*TLB]int)
+	for _, core := range Cores {
+		index, exists := indexByTLB[core.TLB]
+		if !exists {
+			index = len(result)
+			indexByTLB[core.TLB] = index
+			result = append(result, core.TLB.Status())
+		}
+		result[index].Cores = append(result[index].Cores, core.Id)
+	}
+	return result
 }
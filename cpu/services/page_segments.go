@@ -0,0 +1,51 @@
+package services
+
+import (
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// pageSegment es la parte de un acceso a memoria que cae dentro de una única página.
+type pageSegment struct {
+	PageNumber      int
+	Offset          int // desplazamiento dentro de la página
+	PhysicalAddress int
+	Length          int
+	DataOffset      int // desplazamiento dentro del dato completo leído o escrito
+}
+
+// splitAccess divide un acceso de size bytes desde logicalAddress en un segmento por página
+// y traduce cada uno con la TLB del núcleo. Devuelve false si alguna página no pudo traducirse.
+// Un acceso de tamaño cero genera un único segmento vacío para validar la dirección.
+func splitAccess(core *Core, pid uint, logicalAddress int, size int) ([]pageSegment, bool) {
+	pageSize := models.MemConfig.PageSize
+	segments := make([]pageSegment, 0, 1)
+
+	current := logicalAddress
+	remaining := size
+	for {
+		offset := current % pageSize
+		length := pageSize - offset
+		if remaining < length {
+			length = remaining
+		}
+
+		physicalAddress := TranslateAddress(core, pid, current)
+		if physicalAddress == -1 {
+			return nil, false
+		}
+
+		segments = append(segments, pageSegment{
+			PageNumber:      current / pageSize,
+			Offset:          offset,
+			PhysicalAddress: physicalAddress,
+			Length:          length,
+			DataOffset:      current - logicalAddress,
+		})
+
+		current += length
+		remaining -= length
+		if remaining <= 0 {
+			return segments, true
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// Algoritmos de reemplazo soportados por la TLB.
+var tlbAlgorithms = map[string]bool{
+	"FIFO":   true,
+	"LRU":    true,
+	"LFU":    true,
+	"CLOCK":  true,
+	"RANDOM": true,
+	"OPT":    true,
+}
+
+// tlbReference es un acceso a una página, tal como quedó registrado en una traza.
+type tlbReference struct {
+	PID        uint
+	PageNumber int
+}
+
+// loadTLBReferenceTrace lee una traza exportada por la CPU (JSON Lines) y arma la secuencia
+// de páginas accedidas. Se usa para el algoritmo óptimo, que necesita conocer los accesos futuros.
+func loadTLBReferenceTrace(path string) ([]tlbReference, error) {
+	if models.MemConfig == nil || models.MemConfig.PageSize <= 0 {
+		return nil, fmt.Errorf("no se conoce el tamaño de página")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	references := make([]tlbReference, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record models.TraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("línea de traza inválida: %w", err)
+		}
+		if record.LogicalAddress < 0 {
+			continue
+		}
+		references = append(references, tlbReference{
+			PID:        record.PID,
+			PageNumber: record.LogicalAddress / models.MemConfig.PageSize,
+		})
+	}
+	return references, scanner.Err()
+}
+
+// advanceReferences ubica el acceso actual en la traza del algoritmo óptimo.
+// Si el acceso no figura en la traza, la posición no cambia.
+func (tlb *TLB) advanceReferences(pid uint, pageNumber int) {
+	for i := tlb.referencePosition; i < len(tlb.references); i++ {
+		if tlb.references[i].PID == pid && tlb.references[i].PageNumber == pageNumber {
+			tlb.referencePosition = i + 1
+			return
+		}
+	}
+}
+
+// selectVictim devuelve el índice de la entrada a reemplazar según el algoritmo de la TLB.
+// Se asume que el Mutex de la TLB ya fue adquirido.
+func (tlb *TLB) selectVictim() int {
+	switch tlb.Algorithm {
+	case "LRU":
+		return tlb.victimLRU()
+	case "LFU":
+		return tlb.victimLFU()
+	case "CLOCK":
+		return tlb.victimClock()
+	case "RANDOM":
+		return rand.Intn(len(tlb.Entries))
+	case "OPT":
+		return tlb.victimOptimal()
+	default:
+		victimIndex := tlb.FifoPointer
+		tlb.FifoPointer = (tlb.FifoPointer + 1) % tlb.MaxSize // Avanza el puntero circularmente
+		return victimIndex
+	}
+}
+
+func (tlb *TLB) victimLRU() int {
+	victimIndex := 0
+	for i, e := range tlb.Entries {
+		if e.LastUsed < tlb.Entries[victimIndex].LastUsed {
+			victimIndex = i
+		}
+	}
+	return victimIndex
+}
+
+// victimLFU elige la entrada menos accedida. Ante un empate se reemplaza la usada hace más tiempo.
+func (tlb *TLB) victimLFU() int {
+	victimIndex := 0
+	for i, e := range tlb.Entries {
+		victim := tlb.Entries[victimIndex]
+		if e.UseCount < victim.UseCount || (e.UseCount == victim.UseCount && e.LastUsed < victim.LastUsed) {
+			victimIndex = i
+		}
+	}
+	return victimIndex
+}
+
+// victimClock recorre las entradas en forma circular dando una segunda oportunidad a las referenciadas.
+func (tlb *TLB) victimClock() int {
+	for {
+		if tlb.ClockPointer >= len(tlb.Entries) {
+			tlb.ClockPointer = 0
+		}
+		entry := &tlb.Entries[tlb.ClockPointer]
+		if !entry.Referenced {
+			victimIndex := tlb.ClockPointer
+			tlb.ClockPointer = (tlb.ClockPointer + 1) % len(tlb.Entries)
+			return victimIndex
+		}
+		entry.Referenced = false
+		tlb.ClockPointer = (tlb.ClockPointer + 1) % len(tlb.Entries)
+	}
+}
+
+// victimOptimal elige la entrada cuyo próximo uso en la traza está más lejos (o que no se vuelve a usar).
+// Sin traza cargada se comporta como LRU.
+func (tlb *TLB) victimOptimal() int {
+	if len(tlb.references) == 0 {
+		return tlb.victimLRU()
+	}
+
+	victimIndex := 0
+	farthest := -1
+	for i, e := range tlb.Entries {
+		nextUse := len(tlb.references) // no se vuelve a usar
+		for j := tlb.referencePosition; j < len(tlb.references); j++ {
+			if tlb.references[j].PID == e.PID && tlb.references[j].PageNumber == e.PageNumber {
+				nextUse = j
+				break
+			}
+		}
+		if nextUse > farthest {
+			farthest = nextUse
+			victimIndex = i
+		}
+	}
+	return victimIndex
+}
+
+// loadOptimalTrace carga la traza de referencia para el algoritmo óptimo, si corresponde.
+func (tlb *TLB) loadOptimalTrace() {
+	if tlb.Algorithm != "OPT" {
+		return
+	}
+	if models.CpuConfig.TlbOptTrace == "" {
+		slog.Warn("Algoritmo TLB OPT sin tlb_opt_trace configurado. Se usará LRU.")
+		return
+	}
+	references, err := loadTLBReferenceTrace(models.CpuConfig.TlbOptTrace)
+	if err != nil {
+		slog.Error("No se pudo cargar la traza para el algoritmo TLB OPT. Se usará LRU.", "path", models.CpuConfig.TlbOptTrace, "error", err)
+		return
+	}
+	tlb.references = references
+	slog.Debug(fmt.Sprintf("Traza para TLB OPT cargada: %d accesos", len(references)))
+}
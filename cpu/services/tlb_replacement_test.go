@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// newTestTLB arma una TLB llena con una entrada por página del PID 1.
+func newTestTLB(algorithm string, entries ...models.TLBEntry) *TLB {
+	for i := range entries {
+		entries[i].PID = 1
+		entries[i].FrameNumber = entries[i].PageNumber
+	}
+	return &TLB{
+		Entries:   entries,
+		MaxSize:   len(entries),
+		Algorithm: algorithm,
+		Stats:     make(map[uint]*models.TLBPidStats),
+	}
+}
+
+func TestTLB_SelectVictim(t *testing.T) {
+	tests := []struct {
+		name      string
+		tlb       *TLB
+		expected  int
+		clockNext int // Posición esperada del puntero de CLOCK luego de elegir, -1 si no aplica.
+	}{
+		{
+			name: "FIFO reemplaza la entrada del puntero",
+			tlb: func() *TLB {
+				tlb := newTestTLB("FIFO", models.TLBEntry{PageNumber: 0}, models.TLBEntry{PageNumber: 1}, models.TLBEntry{PageNumber: 2})
+				tlb.FifoPointer = 1
+				return tlb
+			}(),
+			expected:  1,
+			clockNext: -1,
+		},
+		{
+			name: "LRU reemplaza la usada hace más tiempo",
+			tlb: newTestTLB("LRU",
+				models.TLBEntry{PageNumber: 0, LastUsed: 5},
+				models.TLBEntry{PageNumber: 1, LastUsed: 2},
+				models.TLBEntry{PageNumber: 2, LastUsed: 9}),
+			expected:  1,
+			clockNext: -1,
+		},
+		{
+			name: "LFU desempata por la usada hace más tiempo",
+			tlb: newTestTLB("LFU",
+				models.TLBEntry{PageNumber: 0, UseCount: 3, LastUsed: 1},
+				models.TLBEntry{PageNumber: 1, UseCount: 1, LastUsed: 5},
+				models.TLBEntry{PageNumber: 2, UseCount: 1, LastUsed: 2}),
+			expected:  2,
+			clockNext: -1,
+		},
+		{
+			name: "CLOCK da segunda oportunidad a las referenciadas",
+			tlb: newTestTLB("CLOCK",
+				models.TLBEntry{PageNumber: 0, Referenced: true},
+				models.TLBEntry{PageNumber: 1, Referenced: false},
+				models.TLBEntry{PageNumber: 2, Referenced: true}),
+			expected:  1,
+			clockNext: 2,
+		},
+		{
+			name: "CLOCK con todas referenciadas da una vuelta completa",
+			tlb: newTestTLB("CLOCK",
+				models.TLBEntry{PageNumber: 0, Referenced: true},
+				models.TLBEntry{PageNumber: 1, Referenced: true},
+				models.TLBEntry{PageNumber: 2, Referenced: true}),
+			expected:  0,
+			clockNext: 1,
+		},
+		{
+			name: "OPT reemplaza la que se usa más tarde",
+			tlb: func() *TLB {
+				tlb := newTestTLB("OPT", models.TLBEntry{PageNumber: 1}, models.TLBEntry{PageNumber: 2}, models.TLBEntry{PageNumber: 3})
+				tlb.references = []tlbReference{{1, 2}, {1, 1}, {1, 3}}
+				return tlb
+			}(),
+			expected:  2,
+			clockNext: -1,
+		},
+		{
+			name: "OPT reemplaza la que no se vuelve a usar",
+			tlb: func() *TLB {
+				tlb := newTestTLB("OPT", models.TLBEntry{PageNumber: 1}, models.TLBEntry{PageNumber: 2}, models.TLBEntry{PageNumber: 3})
+				tlb.references = []tlbReference{{1, 3}, {1, 1}, {1, 3}}
+				return tlb
+			}(),
+			expected:  1,
+			clockNext: -1,
+		},
+		{
+			name: "OPT sin traza se comporta como LRU",
+			tlb: newTestTLB("OPT",
+				models.TLBEntry{PageNumber: 0, LastUsed: 7},
+				models.TLBEntry{PageNumber: 1, LastUsed: 8},
+				models.TLBEntry{PageNumber: 2, LastUsed: 3}),
+			expected:  2,
+			clockNext: -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.tlb.selectVictim(); got != test.expected {
+				t.Errorf("Expected victim %d, got %d", test.expected, got)
+			}
+			if test.clockNext >= 0 && test.tlb.ClockPointer != test.clockNext {
+				t.Errorf("Expected clock pointer %d, got %d", test.clockNext, test.tlb.ClockPointer)
+			}
+		})
+	}
+}
+
+func TestTLB_ClockClearsReferencedBits(t *testing.T) {
+	tlb := newTestTLB("CLOCK",
+		models.TLBEntry{PageNumber: 0, Referenced: true},
+		models.TLBEntry{PageNumber: 1, Referenced: true},
+		models.TLBEntry{PageNumber: 2, Referenced: false})
+
+	tlb.selectVictim()
+
+	for i, referenced := range []bool{false, false, false} {
+		if tlb.Entries[i].Referenced != referenced {
+			t.Errorf("Entrada %d: expected referenced %v, got %v", i, referenced, tlb.Entries[i].Referenced)
+		}
+	}
+}
+
+// La traza avanza con cada búsqueda, así OPT decide según los accesos que todavía no ocurrieron.
+func TestTLB_OptimalFollowsTrace(t *testing.T) {
+	tlb := &TLB{
+		Entries:    make([]models.TLBEntry, 0, 2),
+		MaxSize:    2,
+		Algorithm:  "OPT",
+		Stats:      make(map[uint]*models.TLBPidStats),
+		references: []tlbReference{{1, 1}, {1, 2}, {1, 3}, {1, 1}, {1, 2}},
+	}
+
+	for _, page := range []int{1, 2, 3} {
+		if _, hit := tlb.search(1, page); !hit {
+			tlb.insert(1, page, page)
+		}
+	}
+
+	// Tras acceder a la página 3 la 1 se vuelve a usar antes que la 2: la víctima tuvo que ser la 2.
+	pages := map[int]bool{}
+	for _, entry := range tlb.Entries {
+		pages[entry.PageNumber] = true
+	}
+	if !pages[1] || !pages[3] || pages[2] {
+		t.Errorf("Expected pages 1 and 3 in the TLB, got %v", tlb.Entries)
+	}
+	if tlb.Stats[1].Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", tlb.Stats[1].Evictions)
+	}
+}
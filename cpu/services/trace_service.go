@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+)
+
+// maxTracedProcesses acota los procesos con traza en memoria. Los que migran a otra CPU y finalizan allá
+// nunca se descartan al finalizar en esta, así que al superarlo se descarta el escrito hace más tiempo.
+const maxTracedProcesses = 64
+
+// traceRing es un buffer circular con las últimas instrucciones ejecutadas por un proceso.
+type traceRing struct {
+	records []models.TraceRecord
+	next    int
+	full    bool
+	written uint64 // Orden de la última escritura, para descartar la traza más vieja.
+}
+
+var (
+	traces     = make(map[uint]*traceRing)
+	traceMutex sync.Mutex
+	traceSeq   uint64
+)
+
+// IsEnabledTrace verifica si el registro de trazas está habilitado.
+func IsEnabledTrace() bool {
+	return models.CpuConfig.TraceEntries > 0
+}
+
+// BeginTrace abre el registro de la instrucción que el núcleo está por ejecutar.
+func BeginTrace(core *Core, pid uint, pc int, instruction string) {
+	if !IsEnabledTrace() {
+		return
+	}
+	core.currentTrace = &models.TraceRecord{
+		PID:             pid,
+		Core:            core.Id,
+		PC:              pc,
+		Instruction:     instruction,
+		LogicalAddress:  -1,
+		PhysicalAddress: -1,
+		Timestamp:       time.Now(),
+	}
+}
+
+// EndTrace guarda el registro de la instrucción ejecutada en el buffer del proceso.
+func EndTrace(core *Core) {
+	record := core.currentTrace
+	if record == nil {
+		return
+	}
+	core.currentTrace = nil
+
+	traceMutex.Lock()
+	defer traceMutex.Unlock()
+	ring, exists := traces[record.PID]
+	if !exists {
+		if len(traces) >= maxTracedProcesses {
+			evictOldestTrace()
+		}
+		ring = &traceRing{records: make([]models.TraceRecord, models.CpuConfig.TraceEntries)}
+		traces[record.PID] = ring
+	}
+	traceSeq++
+	ring.written = traceSeq
+	ring.records[ring.next] = *record
+	ring.next = (ring.next + 1) % len(ring.records)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// evictOldestTrace descarta la traza escrita hace más tiempo. Se asume que traceMutex ya fue adquirido.
+func evictOldestTrace() {
+	oldestPID, oldest := uint(0), uint64(0)
+	for pid, ring := range traces {
+		if oldest == 0 || ring.written < oldest {
+			oldestPID, oldest = pid, ring.written
+		}
+	}
+	delete(traces, oldestPID)
+	slog.Debug(fmt.Sprintf("Traza del PID %d descartada por límite de procesos", oldestPID))
+}
+
+// traceTranslation anota en la instrucción actual la traducción realizada y el resultado de la TLB.
+func traceTranslation(core *Core, logicalAddress int, physicalAddress int, tlbResult string) {
+	record := core.currentTrace
+	if record == nil {
+		return
+	}
+	if record.LogicalAddress == -1 {
+		record.LogicalAddress = logicalAddress
+		record.PhysicalAddress = physicalAddress
+	}
+	record.TLB = appendTraceResult(record.TLB, tlbResult)
+}
+
+// traceCache anota en la instrucción actual el resultado del acceso a la caché.
+func traceCache(core *Core, hit bool) {
+	record := core.currentTrace
+	if record == nil {
+		return
+	}
+	result := "MISS"
+	if hit {
+		result = "HIT"
+	}
+	record.Cache = appendTraceResult(record.Cache, result)
+}
+
+// Una instrucción puede acceder a más de una página; los resultados se separan por coma.
+func appendTraceResult(current string, result string) string {
+	if current == "" {
+		return result
+	}
+	return current + "," + result
+}
+
+// GetTrace devuelve las instrucciones registradas de un proceso, de la más vieja a la más nueva.
+func GetTrace(pid uint) []models.TraceRecord {
+	traceMutex.Lock()
+	defer traceMutex.Unlock()
+
+	ring, exists := traces[pid]
+	if !exists {
+		return []models.TraceRecord{}
+	}
+	if !ring.full {
+		result := make([]models.TraceRecord, ring.next)
+		copy(result, ring.records[:ring.next])
+		return result
+	}
+	result := make([]models.TraceRecord, 0, len(ring.records))
+	result = append(result, ring.records[ring.next:]...)
+	result = append(result, ring.records[:ring.next]...)
+	return result
+}
+
+// ExportTrace escribe la traza de un proceso en formato JSON Lines dentro de trace_path.
+func ExportTrace(pid uint) (string, error) {
+	if models.CpuConfig.TracePath == "" {
+		return "", fmt.Errorf("no se configuró trace_path")
+	}
+	if err := os.MkdirAll(models.CpuConfig.TracePath, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(models.CpuConfig.TracePath, fmt.Sprintf("%d-%d.jsonl", pid, models.CpuConfig.PortCpu))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range GetTrace(pid) {
+		if err := encoder.Encode(record); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return "", err
+	}
+
+	slog.Debug(fmt.Sprintf("Traza del PID %d exportada en %s", pid, path))
+	return path, nil
+}
+
+// ExportTraceOnExit exporta la traza cuando el proceso finaliza, si hay un trace_path configurado, y la
+// descarta de memoria: el proceso no vuelve a ejecutar.
+func ExportTraceOnExit(pid uint) {
+	if !IsEnabledTrace() {
+		return
+	}
+	if models.CpuConfig.TracePath != "" {
+		if _, err := ExportTrace(pid); err != nil {
+			slog.Error("Error exportando traza", "pid", pid, "error", err)
+		}
+	}
+
+	traceMutex.Lock()
+	delete(traces, pid)
+	traceMutex.Unlock()
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 
 	ioModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
@@ -10,10 +12,9 @@ import (
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/server"
 )
 
-func SleepHandler() func(http.ResponseWriter, *http.Request) {
+// DeviceRequestHandler recibe una solicitud de I/O del Kernel y la encola hasta que haya un slot libre.
+func DeviceRequestHandler() func(http.ResponseWriter, *http.Request) {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		ioModel.DeviceMutex.Lock()
-
 		//--------- RECIBE ---------
 
 		var deviceRequest kernelModel.DeviceRequest
@@ -25,8 +26,12 @@ func SleepHandler() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
-		//--------- EJECUTA ---------
-		go services.Sleep(deviceRequest.Pid, deviceRequest.SuspensionTime)
+		//--------- ENCOLA ---------
+		if err := services.Enqueue(deviceRequest); err != nil {
+			http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		slog.Debug("Solicitud de I/O encolada", "PID", deviceRequest.Pid, "pendientes", services.PendingRequests())
 
 		//--------- RESPUESTA ---------
 
@@ -34,9 +39,27 @@ func SleepHandler() func(http.ResponseWriter, *http.Request) {
 			Pid:    deviceRequest.Pid,
 			Reason: "Solicitud recibida", //"Fin de IO",
 			Port:   ioModel.IoConfig.PortIo,
+			Ip:     ioModel.IoConfig.IpIo,
 		}
 
 		server.SendJsonResponse(writer, response)
-		ioModel.DeviceMutex.Unlock()
+	}
+}
+
+// CancelHandler cancela la solicitud de I/O de un proceso, esté encolada o en curso.
+func CancelHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var cancelRequest kernelModel.IoCancelRequest
+		if err := json.NewDecoder(request.Body).Decode(&cancelRequest); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !services.Cancel(cancelRequest.Pid) {
+			http.Error(writer, "el proceso no tiene solicitudes en el dispositivo", http.StatusNotFound)
+			return
+		}
+		slog.Info(fmt.Sprintf("## PID: <%d> - Cancelación de IO", cancelRequest.Pid))
+		writer.WriteHeader(http.StatusOK)
 	}
 }
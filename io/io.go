@@ -42,10 +42,18 @@ func main() {
 	log.InitLogger(logPath, models.IoConfig.LogLevel)
 	slog.Info(fmt.Sprintf("Port IO: %d - IO: %s", models.IoConfig.PortIo, models.IoName))
 
+	if err := services.InitDevice(models.IoName, models.IoConfig); err != nil {
+		slog.Error("No se pudo inicializar el dispositivo", "err", err)
+		return
+	}
+	services.InitFaults(models.IoName, models.IoConfig)
+	services.StartWorkers(services.DeviceSlots(models.IoName, models.IoConfig))
+
 	// 1. Definir los handlers ANTES de iniciar el servidor
 	http.HandleFunc("GET /", handlers.HandshakeHandler(fmt.Sprintf("Bienvenido al módulo de IO - Dispositivo: %s", models.IoName)))
 	http.HandleFunc("GET /io", handlers.HandshakeHandler("IO en funcionamiento 🚀"))
-	http.HandleFunc("POST /io", ioHandler.SleepHandler())
+	http.HandleFunc("POST /io", ioHandler.DeviceRequestHandler())
+	http.HandleFunc("POST /io/cancel", ioHandler.CancelHandler())
 
 	// 2. Iniciar el servidor en una goroutine para que no bloquee
 	go func() {
@@ -59,8 +67,9 @@ func main() {
 	// Damos una pequeña pausa para asegurar que la goroutine del servidor haya iniciado
 	time.Sleep(100 * time.Millisecond)
 
-	// 3. AHORA, con el servidor ya escuchando, nos conectamos al Kernel
-	services.ConnectToKernel(models.IoName, models.IoConfig)
+	// 3. AHORA, con el servidor ya escuchando, nos conectamos al Kernel (reintentando hasta que responda)
+	stopHeartbeat := make(chan struct{})
+	go services.MaintainKernelRegistration(models.IoName, models.IoConfig, stopHeartbeat)
 
 	// 4. Mantenemos el proceso principal vivo para manejar señales de cierre
 	signal.Notify(models.Shutdown, syscall.SIGINT, syscall.SIGTERM)
@@ -68,6 +77,11 @@ func main() {
 	sig := <-models.Shutdown
 	slog.Debug("Señal recibida, cerrando módulo IO", "signal", sig)
 
+	close(stopHeartbeat)
+	if models.IoConfig.DrainTimeout > 0 {
+		services.Drain(time.Duration(models.IoConfig.DrainTimeout) * time.Millisecond)
+	}
+
 	// Notifica al Kernel que se cierra este módulo
 	services.NotifyDisconnection()
 
@@ -2,36 +2,90 @@ package models
 
 import (
 	"os"
-	"sync"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	IpKernel   string `json:"ip_kernel"`
-	PortKernel int    `json:"port_kernel"`
-	IpIo       string `json:"ip_io"`
-	PortIo     int    `json:"port_io"`
-	LogLevel   string `json:"log_level"`
+	IpKernel        string                  `json:"ip_kernel"`
+	PortKernel      int                     `json:"port_kernel"`
+	IpIo            string                  `json:"ip_io"`
+	PortIo          int                     `json:"port_io"`
+	IpMemory        string                  `json:"ip_memory"`
+	PortMemory      int                     `json:"port_memory"`
+	LogLevel        string                  `json:"log_level"`
+	DrainTimeout    int                     `json:"drain_timeout"`    // ms para terminar lo que está atendiendo al cerrarse (0 = cierre inmediato).
+	KernelHeartbeat int                     `json:"kernel_heartbeat"` // ms entre registros en el Kernel (0 = solo al iniciar).
+	Devices         map[string]DeviceConfig `json:"devices"`          // Por nombre de dispositivo. Los que no figuran solo esperan.
+}
+
+// Tipos de dispositivo.
+const (
+	DeviceTypeSleep    = "SLEEP"    // Solo espera el tiempo pedido.
+	DeviceTypeDisk     = "DISK"     // Lee y escribe bytes en un archivo local.
+	DeviceTypeTerminal = "TERMINAL" // Lee líneas de stdin o de un archivo de entrada y escribe en stdout.
+)
+
+type DeviceConfig struct {
+	Type       string      `json:"type"`
+	File       string      `json:"file"`        // DISK: archivo que respalda al dispositivo.
+	Input      string      `json:"input"`       // TERMINAL: archivo con las líneas de entrada; vacío para leer de stdin.
+	AccessTime int         `json:"access_time"` // ms que demora cada operación con datos.
+	Slots      int         `json:"slots"`       // Operaciones que atiende a la vez, 1 si no se indica.
+	Faults     FaultConfig `json:"faults"`
+}
+
+// Distribuciones de la latencia inyectada alrededor del tiempo pedido.
+const (
+	LatencyFixed       = "FIXED"       // El tiempo pedido, sin variación.
+	LatencyUniform     = "UNIFORM"     // Uniforme en ± jitter.
+	LatencyNormal      = "NORMAL"      // Normal con desvío jitter.
+	LatencyExponential = "EXPONENTIAL" // Se suma una demora exponencial de media jitter.
+)
+
+// FaultConfig inyecta latencia, errores y desconexiones para probar la robustez del Kernel.
+type FaultConfig struct {
+	Latency         string  `json:"latency"`
+	Jitter          int     `json:"jitter"`           // ms, según la distribución.
+	FailureRate     float64 `json:"failure_rate"`     // Probabilidad de informar un error en lugar del fin de IO.
+	DisconnectAfter int     `json:"disconnect_after"` // Se desconecta luego de N operaciones (0 = nunca).
 }
 
 var IoConfig *Config
-var DeviceMutex sync.Mutex
 
 type Device struct {
 	Name   string
 	Ip     string
 	Port   int
-	IsFree bool
-	PID    uint
+	Slots  int    // Operaciones que la instancia atiende a la vez.
+	BootId string // Arranque del módulo que registró la instancia.
+
+	// Estado que lleva el Kernel.
+	IsFree   bool   // Le queda al menos un slot libre.
+	InUse    int    // Slots reservados o atendiendo.
+	PIDs     []uint // Procesos que está atendiendo.
+	Draining bool   // La instancia se está cerrando y no recibe nuevos procesos.
 }
 
+// Motivos con los que el dispositivo informa el fin de una solicitud.
+const (
+	ReasonFinished    = "Fin de IO"
+	ReasonFailed      = "Error de IO"     // La transferencia de datos falló, el Kernel reintenta o finaliza el proceso según io_error_action.
+	ReasonInterrupted = "IO interrumpida" // El dispositivo se cierra sin atender la solicitud, el Kernel la puede reencolar.
+)
+
 type DeviceResponse struct {
 	Pid    uint
 	Name   string
 	Reason string
 	Port   int
 	Ip     string
+	Data   []byte `json:",omitempty"` // Bytes leídos del dispositivo en DMA_READ.
 }
 
 var IoName string
 
 var Shutdown = make(chan os.Signal, 1)
+
+// BootId identifica este arranque del módulo: si el Kernel lo recibe distinto en la misma dirección, el dispositivo se reinició.
+var BootId = strconv.FormatInt(time.Now().UnixNano(), 36)
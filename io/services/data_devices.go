@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+	kernelModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+)
+
+var errNoDataDevice = errors.New("el dispositivo no transfiere datos")
+
+// dataDevice es un dispositivo que mueve bytes entre la memoria de un proceso y un medio externo.
+type dataDevice interface {
+	// Read devuelve exactamente size bytes leídos desde offset, completando con ceros lo que falte.
+	Read(offset int, size int) ([]byte, error)
+	Write(offset int, data []byte) error
+}
+
+var (
+	activeDevice     dataDevice // nil para los dispositivos que solo esperan.
+	deviceAccessTime time.Duration
+)
+
+// InitDevice prepara el dispositivo según su configuración en io.json.
+func InitDevice(name string, ioConfig *models.Config) error {
+	deviceConfig, found := ioConfig.Devices[name]
+	if !found || deviceConfig.Type == "" || deviceConfig.Type == models.DeviceTypeSleep {
+		return nil
+	}
+	deviceAccessTime = time.Duration(deviceConfig.AccessTime) * time.Millisecond
+
+	switch deviceConfig.Type {
+	case models.DeviceTypeDisk:
+		if deviceConfig.File == "" {
+			return fmt.Errorf("el disco %s no tiene archivo configurado", name)
+		}
+		file, err := os.OpenFile(deviceConfig.File, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		activeDevice = &diskDevice{file: file}
+
+	case models.DeviceTypeTerminal:
+		input := io.Reader(os.Stdin)
+		if deviceConfig.Input != "" {
+			file, err := os.Open(deviceConfig.Input)
+			if err != nil {
+				return err
+			}
+			input = file
+		}
+		activeDevice = &terminalDevice{input: bufio.NewReader(input)}
+
+	default:
+		return fmt.Errorf("tipo de dispositivo desconocido: %s", deviceConfig.Type)
+	}
+
+	slog.Debug("Dispositivo con datos inicializado", "nombre", name, "tipo", deviceConfig.Type)
+	return nil
+}
+
+// transferData resuelve IO_READ (dispositivo → memoria) e IO_WRITE (memoria → dispositivo).
+// La cancelación se respeta entre pasos: una lectura de terminal ya iniciada no se interrumpe, pero sus datos se descartan.
+func transferData(ctx context.Context, request kernelModel.DeviceRequest) error {
+	if activeDevice == nil {
+		return errNoDataDevice
+	}
+	timer := time.NewTimer(injectLatency(deviceAccessTime))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	size := 0
+	for _, transfer := range request.Transfers {
+		size += transfer.Length
+	}
+
+	if request.Operation == kernelModel.SyscallIoRead {
+		data, err := activeDevice.Read(request.Offset, size)
+		if err != nil {
+			return err
+		}
+		position := 0
+		for _, transfer := range request.Transfers {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := writeMemory(request.Pid, transfer.PhysicalAddress, data[position:position+transfer.Length]); err != nil {
+				return err
+			}
+			position += transfer.Length
+		}
+		return nil
+	}
+
+	data := make([]byte, 0, size)
+	for _, transfer := range request.Transfers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		content, err := readMemory(request.Pid, transfer.PhysicalAddress, transfer.Length)
+		if err != nil {
+			return err
+		}
+		data = append(data, content...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return activeDevice.Write(request.Offset, data)
+}
+
+// transferDma resuelve DMA_READ y DMA_WRITE: el Kernel ya leyó de Memoria los datos a escribir y es quien
+// copia a Memoria los datos leídos, que se devuelven para informárselos.
+func transferDma(ctx context.Context, request kernelModel.DeviceRequest) ([]byte, error) {
+	if activeDevice == nil {
+		return nil, errNoDataDevice
+	}
+	timer := time.NewTimer(injectLatency(deviceAccessTime))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if request.Operation == kernelModel.SyscallDmaRead {
+		data, err := activeDevice.Read(request.Offset, request.Size)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	return nil, activeDevice.Write(request.Offset, request.Data)
+}
+
+// --- Disco ---
+
+type diskDevice struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func (disk *diskDevice) Read(offset int, size int) ([]byte, error) {
+	disk.mutex.Lock()
+	defer disk.mutex.Unlock()
+
+	data := make([]byte, size)
+	_, err := disk.file.ReadAt(data, int64(offset))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (disk *diskDevice) Write(offset int, data []byte) error {
+	disk.mutex.Lock()
+	defer disk.mutex.Unlock()
+
+	if _, err := disk.file.WriteAt(data, int64(offset)); err != nil {
+		return err
+	}
+	return disk.file.Sync()
+}
+
+// --- Terminal ---
+
+// terminalDevice ignora el offset: lee la próxima línea de entrada y escribe en la salida estándar.
+type terminalDevice struct {
+	mutex sync.Mutex
+	input *bufio.Reader
+}
+
+func (terminal *terminalDevice) Read(_ int, size int) ([]byte, error) {
+	terminal.mutex.Lock()
+	defer terminal.mutex.Unlock()
+
+	line, err := terminal.input.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, fmt.Errorf("no hay más entrada en la terminal: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	data := make([]byte, size)
+	copy(data, line)
+	return data, nil
+}
+
+func (terminal *terminalDevice) Write(_ int, data []byte) error {
+	terminal.mutex.Lock()
+	defer terminal.mutex.Unlock()
+
+	text := strings.TrimRight(string(data), "\x00")
+	slog.Info(fmt.Sprintf("## Terminal - Salida: <%s>", text))
+	_, err := fmt.Fprintln(os.Stdout, text)
+	return err
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+)
+
+var draining atomic.Bool // Se activa bajo queue.mutex, así ninguna solicitud se encola después de vaciar la cola.
+
+// errDrained es la causa con la que Drain cancela las solicitudes en curso. El slot que la atendía la
+// devuelve al Kernel como interrumpida en lugar de informarla como terminada.
+var errDrained = errors.New("el dispositivo se cerró")
+
+// Espera máxima para que los slots devuelvan las solicitudes canceladas por el cierre: lo que tarda
+// en darse por perdido un aviso al Kernel, más un margen.
+const drainHandBackTimeout = kernelNotifyTimeout + time.Second
+
+// Drain cierra el dispositivo ordenadamente: deja de aceptar solicitudes, devuelve al Kernel las que esperaban
+// un slot y espera hasta timeout a que terminen las que se están atendiendo. Las que no terminan se cancelan
+// y las devuelve el slot que las atendía, para que el Kernel las reencole en otra instancia.
+func Drain(timeout time.Duration) {
+	queue.mutex.Lock()
+	draining.Store(true)
+	pending := queue.pending
+	queue.pending = nil
+	queue.mutex.Unlock()
+
+	slog.Info(fmt.Sprintf("## IO: %s - Cerrando, se terminan las operaciones en curso", models.IoName))
+	notifyDraining()
+	for _, request := range pending {
+		handBack(request.Pid)
+	}
+
+	waitInFlight(timeout)
+
+	queue.mutex.Lock()
+	for _, current := range queue.inFlight {
+		current.cancel(errDrained)
+	}
+	queue.mutex.Unlock()
+
+	// Se espera a que los slots devuelvan lo cancelado antes de avisar al Kernel que el dispositivo se cerró.
+	waitInFlight(drainHandBackTimeout)
+}
+
+// waitInFlight espera hasta timeout a que los slots terminen las solicitudes que están atendiendo.
+func waitInFlight(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for inFlightRequests() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func inFlightRequests() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return len(queue.inFlight)
+}
+
+// notifyDraining avisa al Kernel que no le envíe más procesos a esta instancia.
+func notifyDraining() {
+	request := models.DeviceResponse{Name: models.IoName, Port: models.IoConfig.PortIo, Ip: models.IoConfig.IpIo}
+	sendToKernel("kernel/dispositivo-drenando", request)
+}
+
+// handBack informa al Kernel que la solicitud del proceso no se atendió.
+func handBack(pid uint) {
+	slog.Info(fmt.Sprintf("## PID: <%d> - IO devuelta al Kernel por cierre del dispositivo", pid))
+	request := models.DeviceResponse{Pid: pid, Reason: models.ReasonInterrupted, Port: models.IoConfig.PortIo, Ip: models.IoConfig.IpIo, Name: models.IoName}
+	sendToKernel("kernel/informar-io-finalizada", request)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+)
+
+var (
+	faults             models.FaultConfig
+	completedOperation atomic.Int64
+)
+
+// InitFaults carga la inyección de fallas del dispositivo. Una distribución desconocida se reemplaza por FIXED.
+func InitFaults(name string, ioConfig *models.Config) {
+	faults = ioConfig.Devices[name].Faults
+	switch faults.Latency {
+	case "", models.LatencyFixed, models.LatencyUniform, models.LatencyNormal, models.LatencyExponential:
+	default:
+		slog.Warn("Distribución de latencia desconocida. Se usa FIXED.", "latencia", faults.Latency)
+		faults.Latency = models.LatencyFixed
+	}
+	if (faults.Latency != "" && faults.Latency != models.LatencyFixed) || faults.FailureRate > 0 || faults.DisconnectAfter > 0 {
+		slog.Info(fmt.Sprintf("## IO: %s - Inyección de fallas: latencia <%s> jitter <%d> errores <%.2f> desconexión <%d>",
+			name, faults.Latency, faults.Jitter, faults.FailureRate, faults.DisconnectAfter))
+	}
+}
+
+// injectLatency aplica la distribución configurada al tiempo pedido. Nunca devuelve un tiempo negativo.
+func injectLatency(requested time.Duration) time.Duration {
+	jitter := float64(time.Duration(faults.Jitter) * time.Millisecond)
+	var delta float64
+	switch faults.Latency {
+	case models.LatencyUniform:
+		delta = (rand.Float64()*2 - 1) * jitter
+	case models.LatencyNormal:
+		delta = rand.NormFloat64() * jitter
+	case models.LatencyExponential:
+		delta = rand.ExpFloat64() * jitter
+	}
+	result := requested + time.Duration(delta)
+	if result < 0 {
+		return 0
+	}
+	return result
+}
+
+// injectFailure decide si la operación se informa como fallida.
+func injectFailure() bool {
+	return faults.FailureRate > 0 && rand.Float64() < faults.FailureRate
+}
+
+// countOperation cuenta una operación informada y, al llegar a disconnect_after, dispara el cierre del módulo.
+func countOperation() {
+	if faults.DisconnectAfter <= 0 {
+		return
+	}
+	if completedOperation.Add(1) == int64(faults.DisconnectAfter) {
+		slog.Warn(fmt.Sprintf("## IO: %s - Desconexión programada tras %d operaciones", models.IoName, faults.DisconnectAfter))
+		select {
+		case models.Shutdown <- os.Interrupt:
+		default:
+		}
+	}
+}
@@ -1,86 +1,195 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+	kernelModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
 )
 
-// este servicio realiza la conexión con kernel.
-func ConnectToKernel(ioName string, ioConfig *models.Config) {
+// ConnectToKernel registra el dispositivo en el Kernel. El registro es idempotente:
+// el Kernel reconoce las instancias que ya conoce por su dirección y, por el BootId, si el dispositivo se reinició.
+func ConnectToKernel(ioName string, ioConfig *models.Config) error {
 	//Crea y codifica la request de conexion a Kernel
-	var request = models.Device{Name: ioName, Ip: ioConfig.IpIo, Port: ioConfig.PortIo}
+	var request = models.Device{Name: ioName, Ip: ioConfig.IpIo, Port: ioConfig.PortIo, Slots: DeviceSlots(ioName, ioConfig), BootId: models.BootId}
 	body, err := json.Marshal(request)
-
 	if err != nil {
-		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+		return err
 	}
 
 	//Envia la request de conexion a Kernel
-	_, err = client.DoRequest(ioConfig.PortKernel, ioConfig.IpKernel, "POST", "kernel/dispositivos", body)
-
+	response, err := client.DoRequest(ioConfig.PortKernel, ioConfig.IpKernel, "POST", "kernel/dispositivos", body)
+	if response != nil {
+		response.Body.Close()
+	}
 	if err != nil {
-		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+		return err
 	}
 
-	slog.Debug("Dispositivo registrado exitosamente con el Kernel", "nombre", ioName)
 	slog.Debug(fmt.Sprintf("%%# IO: %s - Registrado en Kernel - IO: %s:%d - Kernel: %s:%d",
 		ioName,
 		ioConfig.IpIo, ioConfig.PortIo,
 		ioConfig.IpKernel, ioConfig.PortKernel))
+	return nil
 }
 
-func notifyKernel(pid uint, message string, ioConfig *models.Config) {
-	//Crea y codifica la request de conexion a Kernel
-	var request = models.DeviceResponse{Pid: pid, Reason: message, Port: ioConfig.PortIo, Name: models.IoName}
-	body, err := json.Marshal(request)
+// MaintainKernelRegistration registra el dispositivo reintentando con backoff hasta que el Kernel responda y
+// luego repite el registro cada kernel_heartbeat ms, para volver a registrarse si el Kernel se reinicia.
+func MaintainKernelRegistration(ioName string, ioConfig *models.Config, stop <-chan struct{}) {
+	backoff := client.Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second}
+	register := func() error { return ConnectToKernel(ioName, ioConfig) }
 
-	if err != nil {
-		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+	if !client.RetryWithBackoff(register, &backoff, stop) {
+		return
 	}
+	slog.Debug("Dispositivo registrado exitosamente con el Kernel", "nombre", ioName)
 
+	if ioConfig.KernelHeartbeat <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ioConfig.KernelHeartbeat) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !client.RetryWithBackoff(register, &backoff, stop) {
+				return
+			}
+		}
+	}
+}
+
+// Tiempo máximo reintentando un aviso al Kernel antes de darlo por perdido.
+const kernelNotifyTimeout = 10 * time.Second
+
+// notifyKernel informa al Kernel el fin de la solicitud del proceso.
+func notifyKernel(pid uint, message string, data []byte, ioConfig *models.Config) {
+	request := models.DeviceResponse{Pid: pid, Reason: message, Port: ioConfig.PortIo, Ip: ioConfig.IpIo, Name: models.IoName, Data: data}
 	slog.Debug("Se envía notificación de finalización de dispositivo.")
-	//Envia la request de conexion a Kernel
-	_, err = client.DoRequest(ioConfig.PortKernel, ioConfig.IpKernel, "POST", "kernel/informar-io-finalizada", body)
+	sendToKernel("kernel/informar-io-finalizada", request)
+}
 
+// sendToKernel envía el aviso al Kernel reintentando con backoff mientras no responda, hasta kernelNotifyTimeout.
+// Si el Kernel lo rechaza no se reintenta, la respuesta sería la misma. Devuelve false si no se entregó.
+func sendToKernel(endpoint string, request models.DeviceResponse) bool {
+	body, err := json.Marshal(request)
 	if err != nil {
-		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+		slog.Error("Error al serializar el aviso al Kernel", "endpoint", endpoint, "PID", request.Pid, "error", err)
+		return false
+	}
+
+	stop := make(chan struct{})
+	timer := time.AfterFunc(kernelNotifyTimeout, func() { close(stop) })
+	defer timer.Stop()
+
+	var rejected error
+	backoff := client.Backoff{Initial: 200 * time.Millisecond, Max: 2 * time.Second}
+	delivered := client.RetryWithBackoff(func() error {
+		response, err := client.DoRequest(models.IoConfig.PortKernel, models.IoConfig.IpKernel, "POST", endpoint, body)
+		if response != nil {
+			response.Body.Close()
+			if err != nil && response.StatusCode < http.StatusInternalServerError {
+				rejected = err
+				return nil
+			}
+		}
+		return err
+	}, &backoff, stop)
+
+	if !delivered {
+		slog.Error("No se pudo avisar al Kernel", "endpoint", endpoint, "PID", request.Pid, "motivo", request.Reason)
+		return false
+	}
+	if rejected != nil {
+		slog.Warn("El Kernel rechazó el aviso", "endpoint", endpoint, "PID", request.Pid, "motivo", request.Reason, "error", rejected)
+		return false
 	}
+	return true
 }
 
-func Sleep(pid uint, suspensionTime int) {
+// Sleep espera el tiempo pedido. Devuelve false si la solicitud se canceló antes de terminar.
+func Sleep(ctx context.Context, pid uint, suspensionTime int) bool {
 	slog.Info(fmt.Sprintf("## PID: <%d> - Inicio de IO - Tiempo: <%d>", pid, suspensionTime))
 	slog.Debug(fmt.Sprintf("[%d] zzzzzzzzzz", pid))
-	time.Sleep(time.Duration(suspensionTime) * time.Millisecond)
+	timer := time.NewTimer(time.Duration(suspensionTime) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return false
+	}
 	slog.Debug("quién me desperto?? (mirada que juzga)")
-	slog.Info(fmt.Sprintf("## PID: <%d> - Fin de IO", pid))
-	notifyKernel(pid, "Fin de IO", models.IoConfig)
+	return true
 }
 
-func NotifyDisconnection() {
-	const InvalidPid uint = 10000000
-	var request = models.DeviceResponse{Pid: InvalidPid, Reason: "KILL", Port: models.IoConfig.PortIo, Name: models.IoName, Ip: models.IoConfig.IpIo}
-
-	body, err := json.Marshal(request)
-
-	if err != nil {
-		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+// Execute atiende una solicitud del Kernel: IO solo espera, IO_READ e IO_WRITE mueven datos con Memoria y
+// DMA_READ y DMA_WRITE intercambian los datos con el Kernel.
+// Una solicitud cancelada por el Kernel no se informa, ya la dio por terminada; una cancelada por el cierre
+// del dispositivo se devuelve como interrumpida aunque la operación haya llegado a completarse.
+func Execute(ctx context.Context, request kernelModel.DeviceRequest) {
+	reason := models.ReasonFinished
+	var data []byte
+	if !kernelModel.IsDataIo(request.Operation) {
+		suspensionTime := injectLatency(time.Duration(request.SuspensionTime) * time.Millisecond)
+		if !Sleep(ctx, request.Pid, int(suspensionTime.Milliseconds())) {
+			reportCancelled(ctx, request.Pid)
+			return
+		}
+	} else {
+		slog.Info(fmt.Sprintf("## PID: <%d> - Inicio de IO - Operación: <%s> - Posición: <%d>", request.Pid, request.Operation, request.Offset))
+		var err error
+		if kernelModel.IsDmaIo(request.Operation) {
+			data, err = transferDma(ctx, request)
+		} else {
+			err = transferData(ctx, request)
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				reportCancelled(ctx, request.Pid)
+				return
+			}
+			slog.Error("Error en la operación de I/O", "PID", request.Pid, "operacion", request.Operation, "error", err)
+			reason = models.ReasonFailed
+		}
 	}
+	if reason == models.ReasonFinished && injectFailure() {
+		slog.Warn(fmt.Sprintf("## PID: <%d> - Falla de IO inyectada", request.Pid))
+		reason = models.ReasonFailed
+	}
+	// La cancelación pudo llegar después del último punto en que la operación la consulta.
+	if ctx.Err() != nil {
+		reportCancelled(ctx, request.Pid)
+		return
+	}
+	slog.Info(fmt.Sprintf("## PID: <%d> - Fin de IO", request.Pid))
+	if reason != models.ReasonFinished {
+		data = nil
+	}
+	notifyKernel(request.Pid, reason, data, models.IoConfig)
+	countOperation()
+}
 
-	_, err = client.DoRequest(models.IoConfig.PortKernel, models.IoConfig.IpKernel, "POST", "kernel/dispositivo-finalizado", body)
-
-	if err != nil {
-		slog.Error(fmt.Sprintf("error: %v", err))
-		panic(err)
+// reportCancelled devuelve al Kernel la solicitud si la canceló el cierre del dispositivo.
+func reportCancelled(ctx context.Context, pid uint) {
+	if errors.Is(context.Cause(ctx), errDrained) {
+		handBack(pid)
+		return
 	}
+	slog.Info(fmt.Sprintf("## PID: <%d> - IO cancelada", pid))
+}
 
+// NotifyDisconnection avisa al Kernel que la instancia se cierra.
+func NotifyDisconnection() {
+	const InvalidPid uint = 10000000
+	var request = models.DeviceResponse{Pid: InvalidPid, Reason: "KILL", Port: models.IoConfig.PortIo, Name: models.IoName, Ip: models.IoConfig.IpIo}
+	sendToKernel("kernel/dispositivo-finalizado", request)
 }
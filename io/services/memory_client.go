@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+	memoriaModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/memoria/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
+)
+
+// readMemory lee size bytes de la memoria física del proceso.
+func readMemory(pid uint, physicalAddress int, size int) ([]byte, error) {
+	readRequest := memoriaModel.ReadRequest{
+		Pid:             pid,
+		PhysicalAddress: physicalAddress,
+		Size:            size,
+	}
+	body, _ := json.Marshal(readRequest)
+	response, err := client.DoRequest(models.IoConfig.PortMemory, models.IoConfig.IpMemory, "POST", "memoria/leerMemoria", body)
+	if err != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+		return nil, fmt.Errorf("error al leer desde Memoria: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		content, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("error al leer desde Memoria: %s", string(content))
+	}
+
+	var memoryResponse struct {
+		Content []byte `json:"content"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&memoryResponse); err != nil {
+		return nil, err
+	}
+	return memoryResponse.Content, nil
+}
+
+// writeMemory escribe los datos en la memoria física del proceso.
+func writeMemory(pid uint, physicalAddress int, data []byte) error {
+	writeRequest := memoriaModel.WriteRequest{
+		Pid:             pid,
+		PhysicalAddress: physicalAddress,
+		Data:            data,
+	}
+	body, _ := json.Marshal(writeRequest)
+	response, err := client.DoRequest(models.IoConfig.PortMemory, models.IoConfig.IpMemory, "POST", "memoria/write", body)
+	if response != nil {
+		response.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("error al escribir en Memoria: %w", err)
+	}
+	return nil
+}
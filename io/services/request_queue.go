@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+	kernelModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+)
+
+// requestQueue guarda las solicitudes del Kernel hasta que un slot del dispositivo pueda atenderlas.
+// Un proceso tiene a lo sumo una solicitud en el dispositivo, por eso se identifican por PID.
+type requestQueue struct {
+	mutex    sync.Mutex
+	notEmpty *sync.Cond
+	pending  []kernelModel.DeviceRequest
+	inFlight map[uint]*inFlightRequest
+}
+
+// inFlightRequest es una solicitud que un slot está atendiendo.
+type inFlightRequest struct {
+	cancel context.CancelCauseFunc // Con errDrained si la cancela el cierre del dispositivo.
+}
+
+var queue = newRequestQueue()
+
+func newRequestQueue() *requestQueue {
+	q := &requestQueue{inFlight: make(map[uint]*inFlightRequest)}
+	q.notEmpty = sync.NewCond(&q.mutex)
+	return q
+}
+
+// DeviceSlots devuelve cuántas operaciones atiende el dispositivo a la vez según io.json.
+func DeviceSlots(name string, ioConfig *models.Config) int {
+	if deviceConfig, found := ioConfig.Devices[name]; found && deviceConfig.Slots > 0 {
+		return deviceConfig.Slots
+	}
+	return 1
+}
+
+// StartWorkers lanza un worker por slot. Cada uno atiende una solicitud por vez en orden de llegada.
+func StartWorkers(slots int) {
+	for slot := 0; slot < slots; slot++ {
+		go worker(slot)
+	}
+	slog.Debug("Slots del dispositivo iniciados", "slots", slots)
+}
+
+// ErrDraining indica que el dispositivo se está cerrando y ya no acepta solicitudes.
+var ErrDraining = errors.New("el dispositivo se está cerrando")
+
+// Enqueue agrega una solicitud a la cola del dispositivo. Si el dispositivo se está cerrando devuelve
+// ErrDraining; se verifica bajo el lock de la cola para que Drain no deje atrás una solicitud recién encolada.
+func Enqueue(request kernelModel.DeviceRequest) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if draining.Load() {
+		return ErrDraining
+	}
+	queue.pending = append(queue.pending, request)
+	queue.notEmpty.Signal()
+	return nil
+}
+
+// PendingRequests devuelve la cantidad de solicitudes que esperan un slot libre.
+func PendingRequests() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return len(queue.pending)
+}
+
+// Cancel descarta la solicitud del proceso si está encolada o interrumpe la que se está atendiendo.
+// Devuelve false si el proceso no tenía ninguna solicitud en el dispositivo.
+func Cancel(pid uint) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if index := slices.IndexFunc(queue.pending, func(request kernelModel.DeviceRequest) bool { return request.Pid == pid }); index >= 0 {
+		queue.pending = slices.Delete(queue.pending, index, index+1)
+		slog.Debug("Solicitud de I/O encolada cancelada", "PID", pid)
+		return true
+	}
+	if current, found := queue.inFlight[pid]; found {
+		current.cancel(nil)
+		delete(queue.inFlight, pid)
+		slog.Debug("Solicitud de I/O en curso cancelada", "PID", pid)
+		return true
+	}
+	return false
+}
+
+func worker(slot int) {
+	for {
+		queue.mutex.Lock()
+		for len(queue.pending) == 0 {
+			queue.notEmpty.Wait()
+		}
+		request := queue.pending[0]
+		queue.pending = queue.pending[1:]
+		ctx, cancel := context.WithCancelCause(context.Background())
+		current := &inFlightRequest{cancel: cancel}
+		queue.inFlight[request.Pid] = current
+		queue.mutex.Unlock()
+
+		slog.Debug("Slot atendiendo solicitud", "slot", slot, "PID", request.Pid)
+		Execute(ctx, request)
+
+		// Si se canceló, el Kernel pudo haber reenviado otra solicitud del mismo proceso a otro slot.
+		queue.mutex.Lock()
+		if queue.inFlight[request.Pid] == current {
+			delete(queue.inFlight, request.Pid)
+		}
+		queue.mutex.Unlock()
+		cancel(nil)
+	}
+}
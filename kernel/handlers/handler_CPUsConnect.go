@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strconv"
 
 	cpuModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
-	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/services"
 )
 
 // ConnectCpuHandler maneja las solicitudes de conexión de nuevas CPUs.
@@ -22,13 +21,29 @@ func ConnectCpuHandler() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
-		cpuConnected.IsFree = true
+		// El registro es idempotente: los heartbeats de una CPU ya conocida no pisan su estado.
+		if !services.RegisterCpu(&cpuConnected) {
+			writer.WriteHeader(http.StatusOK)
+			return
+		}
+		slog.Debug(fmt.Sprintf("CPU conectada: ID=%d Núcleo=%d en %s:%d", cpuConnected.Id, cpuConnected.Core, cpuConnected.Ip, cpuConnected.Port))
+		services.StartShortTermScheduler()
+
+		writer.WriteHeader(http.StatusOK)
+	}
+}
 
-		slog.Debug(fmt.Sprintf("CPU conectada: ID=%d en %s:%d", cpuConnected.Id, cpuConnected.Ip, cpuConnected.Port))
+// DisconnectCpuHandler da de baja todos los núcleos de una CPU que se está cerrando.
+func DisconnectCpuHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var cpuDisconnected cpuModel.CpuN
+		if err := json.NewDecoder(request.Body).Decode(&cpuDisconnected); err != nil {
+			http.Error(writer, "Error decodificando los datos de la CPU", http.StatusBadRequest)
+			return
+		}
 
-		// Usamos nuestro helper seguro para guardar la nueva CPU.
-		key := strconv.Itoa(cpuConnected.Id)
-		models.ConnectedCpuMap.Set(key, &cpuConnected)
+		slog.Debug(fmt.Sprintf("CPU desconectada: ID=%d en %s:%d", cpuDisconnected.Id, cpuDisconnected.Ip, cpuDisconnected.Port))
+		services.DeregisterCpu(cpuDisconnected.Ip, cpuDisconnected.Port, "la CPU se desconectó")
 
 		writer.WriteHeader(http.StatusOK)
 	}
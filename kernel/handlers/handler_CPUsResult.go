@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/services"
+)
+
+// BurstResultHandler recibe el resultado de una ráfaga cuando el proceso abandona la CPU.
+func BurstResultHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var result models.PCBExecuteRequest
+		if err := json.NewDecoder(request.Body).Decode(&result); err != nil {
+			http.Error(writer, "Error decodificando el resultado de la ráfaga", http.StatusBadRequest)
+			return
+		}
+
+		if err := services.HandleBurstCompletion(result); err != nil {
+			if errors.Is(err, services.ErrStaleDispatch) {
+				slog.Warn("Se rechaza un resultado de ráfaga obsoleto.", "PID", result.PID, "despacho", result.DispatchId)
+				http.Error(writer, err.Error(), http.StatusConflict)
+				return
+			}
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/services"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/list"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/server"
 )
 
 // ConnectIoHandler maneja la conexión de un nuevo dispositivo de I/O.
@@ -21,9 +22,22 @@ func ConnectIoHandler() func(http.ResponseWriter, *http.Request) {
 			http.Error(w, "Datos de dispositivo inválidos", http.StatusBadRequest)
 			return
 		}
-		device.IsFree = true
-		models.ConnectedDeviceManager.Add(&device)
-		slog.Info("Dispositivo de I/O conectado", "nombre", device.Name, "puerto", device.Port)
+		registered, replaced := models.ConnectedDeviceManager.Register(&device)
+		if !registered {
+			slog.Debug("Dispositivo de I/O ya registrado", "nombre", device.Name, "puerto", device.Port)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		slog.Info("Dispositivo de I/O conectado", "nombre", device.Name, "puerto", device.Port, "slots", device.Slots)
+
+		// Si reemplaza a una instancia de un arranque anterior, las operaciones que atendía se perdieron:
+		// se resuelven como interrumpidas, así se reencolan o se finalizan según io_requeue_on_drain.
+		if replaced != nil {
+			slog.Info("Dispositivo de I/O reiniciado", "nombre", replaced.Name, "puerto", replaced.Port, "procesos", len(replaced.PIDs))
+			for _, pid := range replaced.PIDs {
+				services.HandleInterruptedIo(pid)
+			}
+		}
 
 		// CORRECCIÓN: Damos una pequeña pausa para que el servidor del I/O termine de levantarse.
 		// Esto previene la condición de carrera al conectar un nuevo dispositivo.
@@ -45,15 +59,40 @@ func FinishIoHandler() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
-		slog.Info(fmt.Sprintf("## PID: (<%d>) finalizó IO y pasa a READY", response.Pid))
+		switch response.Reason {
+		case ioModel.ReasonInterrupted:
+			services.HandleInterruptedIo(response.Pid)
+			w.WriteHeader(http.StatusOK)
+			return
+		case ioModel.ReasonFailed:
+			services.HandleFailedIo(response.Pid)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		pending, err := services.CompleteIo(response.Pid, response.Data)
+		if !pending {
+			slog.Warn("Se ignora el fin de una operación de I/O vencida o cancelada.", "PID", response.Pid, "puerto", response.Port)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 
-		device, found := models.ConnectedDeviceManager.MarkAsFreeByPort(response.Port)
+		device, found := models.ConnectedDeviceManager.Release(response.Ip, response.Port, response.Pid)
 		if !found {
 			slog.Warn("Se recibió fin de I/O de un dispositivo no registrado.", "puerto", response.Port)
 		}
 
-		// CORRECCIÓN: Usamos la nueva función de desbloqueo inteligente UnblockProcessAfterIO.
-		services.UnblockProcessAfterIO(response.Pid)
+		if err != nil {
+			slog.Error("No se pudieron copiar a Memoria los datos de la transferencia DMA. Finalizando proceso.", "PID", response.Pid, "error", err)
+			if pcb, exists := services.FindPCBInAnyQueue(response.Pid); exists {
+				services.TransitionProcessState(pcb, models.EstadoExit)
+				services.StartLongTermScheduler()
+			}
+		} else {
+			slog.Info(fmt.Sprintf("## PID: (<%d>) finalizó IO y pasa a READY", response.Pid))
+			// CORRECCIÓN: Usamos la nueva función de desbloqueo inteligente UnblockProcessAfterIO.
+			services.UnblockProcessAfterIO(response.Pid)
+		}
 
 		// Intentamos despachar al siguiente proceso en la cola de espera.
 		if found {
@@ -64,6 +103,24 @@ func FinishIoHandler() func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// DrainingIoHandler marca una instancia de I/O que se está cerrando para que no reciba nuevos procesos.
+func DrainingIoHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var response ioModel.DeviceResponse
+		if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+			http.Error(w, "Respuesta de dispositivo inválida", http.StatusBadRequest)
+			return
+		}
+
+		if _, found := models.ConnectedDeviceManager.SetDraining(response.Ip, response.Port); !found {
+			http.Error(w, "Dispositivo no registrado", http.StatusNotFound)
+			return
+		}
+		slog.Info("## Dispositivo de I/O cerrándose", "nombre", response.Name, "puerto", response.Port)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
 // DisconnectIoHandler maneja la desconexión de un dispositivo de I/O.
 func DisconnectIoHandler() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -75,22 +132,25 @@ func DisconnectIoHandler() func(http.ResponseWriter, *http.Request) {
 
 		slog.Info("## Dispositivo de I/O desconectado", "nombre", response.Name, "puerto", response.Port)
 
-		// 1. Identificar el proceso que estaba en ejecución en este dispositivo y finalizarlo
-		executingPID := models.ConnectedDeviceManager.GetPidByPort(response.Port)
-		if executingPID != 0 {
+		// 1. Identificar los procesos que estaba atendiendo este dispositivo y remover el dispositivo de la lista
+		// de conectados, así al cancelar sus operaciones el slot no se reasigna a otro proceso en espera.
+		executingPIDs := models.ConnectedDeviceManager.GetPidsByAddress(response.Ip, response.Port)
+		models.ConnectedDeviceManager.RemoveByAddress(response.Ip, response.Port)
+
+		// 2. Finalizar esos procesos, descartando antes su operación en curso para que no venza su plazo
+		// ni se complete tarde.
+		for _, executingPID := range executingPIDs {
 			pcb, found := services.FindPCBInAnyQueue(executingPID)
 			if found {
 				slog.Debug("Finalizando proceso en ejecución por desconexión de IO", "PID", pcb.PID, "dispositivo", response.Name)
+				services.CancelProcessIo(pcb)
 				services.TransitionProcessState(pcb, models.EstadoExit)
 				services.StartLongTermScheduler()
 			}
 		}
 
-		// 2. Remover el dispositivo de la lista de conectados
-		models.ConnectedDeviceManager.RemoveByPort(response.Port)
-
 		// 3. Verificar si quedan más instancias de este tipo de dispositivo
-		_, exists := models.ConnectedDeviceManager.GetFreeByName(response.Name)
+		exists := models.ConnectedDeviceManager.Exists(response.Name)
 
 		// 4. Si no quedan más instancias, finalizar todos los procesos en espera para este dispositivo
 		if !exists {
@@ -130,3 +190,17 @@ func DisconnectIoHandler() func(http.ResponseWriter, *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// DeviceStatsHandler devuelve las métricas acumuladas de I/O de cada dispositivo.
+func DeviceStatsHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.SendJsonResponse(w, services.GetDeviceStats())
+	}
+}
+
+// DeviceInstancesHandler lista las instancias de I/O conectadas con el proceso que atienden y su utilización.
+func DeviceInstancesHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.SendJsonResponse(w, services.GetDeviceInstances())
+	}
+}
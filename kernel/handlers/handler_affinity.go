@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/services"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/server"
+)
+
+// SetAffinityHandler fija la afinidad estricta de un proceso a una CPU.
+func SetAffinityHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var affinityRequest models.AffinityRequest
+		if err := json.NewDecoder(request.Body).Decode(&affinityRequest); err != nil {
+			http.Error(writer, "Error decodificando la afinidad", http.StatusBadRequest)
+			return
+		}
+
+		if err := services.SetHardAffinity(affinityRequest); err != nil {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	}
+}
+
+// ClearAffinityHandler quita la afinidad estricta de un proceso.
+func ClearAffinityHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		pid, err := strconv.ParseUint(request.URL.Query().Get("pid"), 10, 64)
+		if err != nil {
+			http.Error(writer, "PID inválido", http.StatusBadRequest)
+			return
+		}
+
+		if err := services.ClearHardAffinity(uint(pid)); err != nil {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	}
+}
+
+// AffinityStatsHandler devuelve las estadísticas de afinidad de los despachos.
+func AffinityStatsHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		server.SendJsonResponse(writer, services.GetAffinityStats())
+	}
+}
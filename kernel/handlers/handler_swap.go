@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/services"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/server"
+)
+
+// SwapStatsHandler devuelve la profundidad de la cola de SWAP y la latencia de sus operaciones.
+func SwapStatsHandler() func(http.ResponseWriter, *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		server.SendJsonResponse(writer, services.GetSwapStats())
+	}
+}
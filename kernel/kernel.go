@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 
 	kernelHandler "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/handlers"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
@@ -34,11 +36,15 @@ func main() {
 	log.InitLogger(LogPath, models.KernelConfig.LogLevel)
 
 	slog.Info(fmt.Sprintf("Kernel escuchando en el puerto: %d", models.KernelConfig.PortKernel))
+	models.ConnectedDeviceManager.SetBalancing(models.KernelConfig.IoBalancing)
+	models.WaitingForDeviceManager.SetPolicies(models.KernelConfig.IoScheduling)
 
 	// --- 2. Inicio de Planificadores ---
 	go services.StartScheduler()      // Inicia el PLP (esperará el Enter).
 	go services.ShortTermScheduler()  // Inicia el PCP (esperará notificaciones).
 	go services.MediumTermScheduler() // Inicia el PMP (esperará notificaciones y timers).
+	services.StartSwapWorkers(models.KernelConfig.SwapWorkers)
+	go services.StartCpuHealthCheck() // Da de baja las CPUs que dejan de responder.
 
 	// --- 3. Creación del Proceso Inicial ---
 	pseudocodeFile := os.Args[1]
@@ -61,18 +67,43 @@ func main() {
 
 	// Conexión de recursos
 	http.HandleFunc("POST /kernel/cpus", kernelHandler.ConnectCpuHandler())
+	http.HandleFunc("POST /kernel/cpu-finalizada", kernelHandler.DisconnectCpuHandler())
+	http.HandleFunc("POST /kernel/resultado-rafaga", kernelHandler.BurstResultHandler())
 	http.HandleFunc("POST /kernel/dispositivos", kernelHandler.ConnectIoHandler())
+	http.HandleFunc("GET /kernel/dispositivos", kernelHandler.DeviceStatsHandler())
+	http.HandleFunc("GET /kernel/dispositivos/instancias", kernelHandler.DeviceInstancesHandler())
 
 	// Syscalls y notificaciones
 	http.HandleFunc("POST /kernel/syscall/init_proc", kernelHandler.InitProcSyscallHandler())
 	http.HandleFunc("POST /kernel/informar-io-finalizada", kernelHandler.FinishIoHandler())
 	// Endpoint para manejar la desconexión de un dispositivo de I/O
+	http.HandleFunc("POST /kernel/dispositivo-drenando", kernelHandler.DrainingIoHandler())
 	http.HandleFunc("POST /kernel/dispositivo-finalizado", kernelHandler.DisconnectIoHandler())
 
+	// Estado de la cola de SWAP
+	http.HandleFunc("GET /kernel/swap", kernelHandler.SwapStatsHandler())
+
+	// Afinidad de procesos con CPUs
+	http.HandleFunc("GET /kernel/afinidad", kernelHandler.AffinityStatsHandler())
+	http.HandleFunc("POST /kernel/afinidad", kernelHandler.SetAffinityHandler())
+	http.HandleFunc("DELETE /kernel/afinidad", kernelHandler.ClearAffinityHandler())
+
 	// --- 5. Arranque del Servidor ---
-	err = server.InitServer(models.KernelConfig.PortKernel)
-	if err != nil {
-		slog.Error(fmt.Sprintf("Error al iniciar el servidor del Kernel: %v", err))
-		panic(err)
-	}
+	go func() {
+		err := server.InitServer(models.KernelConfig.PortKernel)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error al iniciar el servidor del Kernel: %v", err))
+			panic(err)
+		}
+	}()
+
+	// --- 6. Cierre ---
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-shutdown
+	slog.Debug("Señal recibida, cerrando el Kernel", "signal", sig)
+
+	services.LogDeviceStats()
+	services.LogSwapStats()
+	os.Exit(0)
 }
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	ioModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+)
+
+// DeviceStats resume la actividad de un dispositivo (todas sus instancias) desde que arrancó el kernel.
+type DeviceStats struct {
+	Name           string          `json:"name"`
+	Served         int             `json:"served"`
+	BusyTimeMs     int64           `json:"busy_time_ms"` // Suma del tiempo de servicio de todas las solicitudes.
+	Waits          int             `json:"waits"`
+	AverageWaitMs  float64         `json:"average_wait_ms"`
+	QueueLength    int             `json:"queue_length"`
+	MaxQueueLength int             `json:"max_queue_length"`
+	Instances      []InstanceStats `json:"instances"`
+	PidTimeMs      map[uint]int64  `json:"pid_time_ms"`
+}
+
+// InstanceStats resume la actividad de una instancia, identificada por ip:puerto.
+type InstanceStats struct {
+	Ip         string `json:"ip"`
+	Port       int    `json:"port"`
+	Served     int    `json:"served"`
+	BusyTimeMs int64  `json:"busy_time_ms"`
+}
+
+type deviceAccount struct {
+	served    int
+	busyTime  time.Duration
+	waitTime  time.Duration
+	waits     int
+	maxQueue  int
+	instances map[string]*instanceAccount
+	pidTime   map[uint]time.Duration
+}
+
+type instanceAccount struct {
+	ip        string
+	port      int
+	served    int
+	busyTime  time.Duration
+	inService map[uint]time.Time // Inicio del servicio de cada proceso que está atendiendo.
+}
+
+// IoAccounting acumula las métricas de I/O por dispositivo. A diferencia de DeviceManager, conserva los
+// datos de las instancias que ya se desconectaron para poder mostrarlos al cerrar el kernel.
+type IoAccounting struct {
+	mx      sync.Mutex
+	devices map[string]*deviceAccount
+}
+
+func NewIoAccounting() *IoAccounting {
+	return &IoAccounting{devices: make(map[string]*deviceAccount)}
+}
+
+// account requiere ia.mx tomado.
+func (ia *IoAccounting) account(name string) *deviceAccount {
+	account, found := ia.devices[name]
+	if !found {
+		account = &deviceAccount{
+			instances: make(map[string]*instanceAccount),
+			pidTime:   make(map[uint]time.Duration),
+		}
+		ia.devices[name] = account
+	}
+	return account
+}
+
+// instance requiere ia.mx tomado.
+func (ia *IoAccounting) instance(device *ioModels.Device) *instanceAccount {
+	account := ia.account(device.Name)
+	key := fmt.Sprintf("%s:%d", device.Ip, device.Port)
+	instance, found := account.instances[key]
+	if !found {
+		instance = &instanceAccount{ip: device.Ip, port: device.Port, inService: make(map[uint]time.Time)}
+		account.instances[key] = instance
+	}
+	return instance
+}
+
+// RecordQueueLength registra la longitud de la cola de espera del dispositivo para llevar su máximo.
+func (ia *IoAccounting) RecordQueueLength(name string, length int) {
+	ia.mx.Lock()
+	defer ia.mx.Unlock()
+	account := ia.account(name)
+	account.maxQueue = max(account.maxQueue, length)
+}
+
+// RecordWait registra cuánto esperó un proceso en la cola antes de ser despachado al dispositivo.
+func (ia *IoAccounting) RecordWait(name string, waited time.Duration) {
+	ia.mx.Lock()
+	defer ia.mx.Unlock()
+	account := ia.account(name)
+	account.waitTime += waited
+	account.waits++
+}
+
+// StartService registra que la instancia empieza a atender al proceso.
+func (ia *IoAccounting) StartService(device *ioModels.Device, pid uint) {
+	ia.mx.Lock()
+	defer ia.mx.Unlock()
+	ia.instance(device).inService[pid] = time.Now()
+}
+
+// FinishService registra que la instancia dejó de atender al proceso, sea cual sea el resultado.
+func (ia *IoAccounting) FinishService(device *ioModels.Device, pid uint) {
+	ia.mx.Lock()
+	defer ia.mx.Unlock()
+	instance := ia.instance(device)
+	startedAt, found := instance.inService[pid]
+	if !found {
+		return
+	}
+	delete(instance.inService, pid)
+	elapsed := time.Since(startedAt)
+
+	account := ia.account(device.Name)
+	account.served++
+	account.busyTime += elapsed
+	account.pidTime[pid] += elapsed
+	instance.served++
+	instance.busyTime += elapsed
+}
+
+// Snapshot devuelve las métricas de todos los dispositivos, ordenados por nombre.
+// El tiempo de las solicitudes en curso se cuenta hasta el momento de la consulta.
+// La longitud actual de la cola la completa quien la consulta.
+func (ia *IoAccounting) Snapshot() []DeviceStats {
+	ia.mx.Lock()
+	defer ia.mx.Unlock()
+
+	now := time.Now()
+	stats := []DeviceStats{}
+	for name, account := range ia.devices {
+		deviceStats := DeviceStats{
+			Name:           name,
+			Served:         account.served,
+			Waits:          account.waits,
+			MaxQueueLength: account.maxQueue,
+			Instances:      []InstanceStats{},
+			PidTimeMs:      make(map[uint]int64),
+		}
+		busy := account.busyTime
+		pidTime := make(map[uint]time.Duration, len(account.pidTime))
+		for pid, elapsed := range account.pidTime {
+			pidTime[pid] = elapsed
+		}
+		for _, instance := range account.instances {
+			instanceBusy := instance.busyTime
+			for pid, startedAt := range instance.inService {
+				elapsed := now.Sub(startedAt)
+				instanceBusy += elapsed
+				busy += elapsed
+				pidTime[pid] += elapsed
+			}
+			deviceStats.Instances = append(deviceStats.Instances, InstanceStats{
+				Ip:         instance.ip,
+				Port:       instance.port,
+				Served:     instance.served,
+				BusyTimeMs: instanceBusy.Milliseconds(),
+			})
+		}
+		sort.Slice(deviceStats.Instances, func(i, j int) bool {
+			if deviceStats.Instances[i].Ip != deviceStats.Instances[j].Ip {
+				return deviceStats.Instances[i].Ip < deviceStats.Instances[j].Ip
+			}
+			return deviceStats.Instances[i].Port < deviceStats.Instances[j].Port
+		})
+		for pid, elapsed := range pidTime {
+			deviceStats.PidTimeMs[pid] = elapsed.Milliseconds()
+		}
+		deviceStats.BusyTimeMs = busy.Milliseconds()
+		if account.waits > 0 {
+			deviceStats.AverageWaitMs = float64(account.waitTime.Milliseconds()) / float64(account.waits)
+		}
+		stats = append(stats, deviceStats)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
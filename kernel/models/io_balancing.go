@@ -0,0 +1,156 @@
+package models
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	ioModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+)
+
+// Estrategias para elegir entre varias instancias libres de un mismo dispositivo.
+const (
+	IoBalanceFirstFree  = "FIRST_FREE"  // La primera instancia libre en orden de conexión.
+	IoBalanceRoundRobin = "ROUND_ROBIN" // Rota entre las instancias.
+	IoBalanceLeastUsed  = "LEAST_USED"  // La instancia que atendió menos procesos.
+	IoBalanceLeastBusy  = "LEAST_BUSY"  // La instancia con menos tiempo total ocupada.
+)
+
+var ioBalancingStrategies = map[string]bool{
+	IoBalanceFirstFree:  true,
+	IoBalanceRoundRobin: true,
+	IoBalanceLeastUsed:  true,
+	IoBalanceLeastBusy:  true,
+}
+
+// DeviceUsage acumula la utilización de una instancia de dispositivo.
+type DeviceUsage struct {
+	ConnectedAt time.Time
+	Dispatches  int
+	BusyTime    time.Duration
+	busySince   time.Time // Cero si la instancia está libre.
+}
+
+// DeviceInstanceStatus describe una instancia conectada para el endpoint de instancias.
+type DeviceInstanceStatus struct {
+	Name        string  `json:"name"`
+	Ip          string  `json:"ip"`
+	Port        int     `json:"port"`
+	Free        bool    `json:"free"`
+	Draining    bool    `json:"draining"`
+	Slots       int     `json:"slots"`
+	PIDs        []uint  `json:"pids"`
+	QueueLength int     `json:"queue_length"`
+	Dispatches  int     `json:"dispatches"`
+	BusyTimeMs  int64   `json:"busy_time_ms"`
+	Utilization float64 `json:"utilization"`
+}
+
+// SetBalancing fija la estrategia de balanceo. Una estrategia desconocida se reemplaza por FIRST_FREE.
+func (dm *DeviceManager) SetBalancing(strategy string) {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	if strategy == "" {
+		strategy = IoBalanceFirstFree
+	}
+	if !ioBalancingStrategies[strategy] {
+		slog.Warn("Estrategia de balanceo de I/O desconocida. Se usa FIRST_FREE.", "estrategia", strategy)
+		strategy = IoBalanceFirstFree
+	}
+	dm.balancing = strategy
+}
+
+// selectInstance devuelve el índice de la instancia libre elegida, o -1 si no hay ninguna. Requiere dm.mx tomado.
+func (dm *DeviceManager) selectInstance(name string, deviceList []*ioModels.Device) int {
+	switch dm.balancing {
+	case IoBalanceRoundRobin:
+		start := dm.nextIndex[name]
+		for i := range deviceList {
+			index := (start + i) % len(deviceList)
+			if deviceList[index].IsFree {
+				dm.nextIndex[name] = (index + 1) % len(deviceList)
+				return index
+			}
+		}
+		return -1
+
+	case IoBalanceLeastUsed:
+		return dm.selectMinimum(deviceList, func(usage *DeviceUsage) int64 { return int64(usage.Dispatches) })
+
+	case IoBalanceLeastBusy:
+		return dm.selectMinimum(deviceList, func(usage *DeviceUsage) int64 { return int64(usage.BusyTime) })
+
+	default:
+		for index, device := range deviceList {
+			if device.IsFree {
+				return index
+			}
+		}
+		return -1
+	}
+}
+
+// selectMinimum elige la instancia libre con el menor valor de la métrica. Ante un empate gana la primera.
+func (dm *DeviceManager) selectMinimum(deviceList []*ioModels.Device, metric func(*DeviceUsage) int64) int {
+	selected := -1
+	var best int64
+	for index, device := range deviceList {
+		if !device.IsFree {
+			continue
+		}
+		var value int64
+		if usage, found := dm.usage[DeviceKey(device.Ip, device.Port)]; found {
+			value = metric(usage)
+		}
+		if selected < 0 || value < best {
+			selected, best = index, value
+		}
+	}
+	return selected
+}
+
+// Instances devuelve el estado de todas las instancias conectadas, ordenadas por nombre, ip y puerto.
+// La longitud de la cola de espera la completa quien la consulta.
+func (dm *DeviceManager) Instances() []DeviceInstanceStatus {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+
+	now := time.Now()
+	instances := []DeviceInstanceStatus{}
+	for _, deviceList := range dm.devices {
+		for _, device := range deviceList {
+			status := DeviceInstanceStatus{
+				Name:     device.Name,
+				Ip:       device.Ip,
+				Port:     device.Port,
+				Free:     device.IsFree,
+				Draining: device.Draining,
+				Slots:    device.Slots,
+				PIDs:     append([]uint{}, device.PIDs...),
+			}
+			if usage, found := dm.usage[DeviceKey(device.Ip, device.Port)]; found {
+				busy := usage.BusyTime
+				if !usage.busySince.IsZero() {
+					busy += now.Sub(usage.busySince)
+				}
+				status.Dispatches = usage.Dispatches
+				status.BusyTimeMs = busy.Milliseconds()
+				if connected := now.Sub(usage.ConnectedAt); connected > 0 {
+					status.Utilization = float64(busy) / float64(connected)
+				}
+			}
+			instances = append(instances, status)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Name != instances[j].Name {
+			return instances[i].Name < instances[j].Name
+		}
+		if instances[i].Ip != instances[j].Ip {
+			return instances[i].Ip < instances[j].Ip
+		}
+		return instances[i].Port < instances[j].Port
+	})
+	return instances
+}
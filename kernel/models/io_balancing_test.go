@@ -0,0 +1,161 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	ioModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+)
+
+// newTestDeviceManager registra una instancia del dispositivo DISCO por cada puerto, en ese orden.
+func newTestDeviceManager(strategy string, ports ...int) *DeviceManager {
+	dm := NewDeviceManager()
+	dm.SetBalancing(strategy)
+	for _, port := range ports {
+		dm.Register(&ioModels.Device{Name: "DISCO", Ip: "127.0.0.1", Port: port})
+	}
+	return dm
+}
+
+func TestDeviceManager_GetFreeByName(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		prepare  func(*DeviceManager)
+		expected []int // Puertos elegidos en reservas sucesivas, liberando cada una antes de la siguiente.
+	}{
+		{
+			name:     "FIRST_FREE elige siempre la primera libre",
+			strategy: IoBalanceFirstFree,
+			expected: []int{8001, 8001, 8001},
+		},
+		{
+			name:     "ROUND_ROBIN rota entre las instancias",
+			strategy: IoBalanceRoundRobin,
+			expected: []int{8001, 8002, 8003, 8001},
+		},
+		{
+			name:     "ROUND_ROBIN saltea las ocupadas",
+			strategy: IoBalanceRoundRobin,
+			prepare: func(dm *DeviceManager) {
+				dm.findByAddress("127.0.0.1", 8002).IsFree = false
+			},
+			expected: []int{8001, 8003, 8001},
+		},
+		{
+			name:     "LEAST_USED elige la que atendió menos procesos",
+			strategy: IoBalanceLeastUsed,
+			prepare: func(dm *DeviceManager) {
+				dm.usage[DeviceKey("127.0.0.1", 8001)].Dispatches = 4
+				dm.usage[DeviceKey("127.0.0.1", 8002)].Dispatches = 1
+				dm.usage[DeviceKey("127.0.0.1", 8003)].Dispatches = 2
+			},
+			expected: []int{8002, 8002, 8003},
+		},
+		{
+			name:     "LEAST_BUSY elige la que estuvo menos tiempo ocupada",
+			strategy: IoBalanceLeastBusy,
+			prepare: func(dm *DeviceManager) {
+				dm.usage[DeviceKey("127.0.0.1", 8001)].BusyTime = 3 * time.Second
+				dm.usage[DeviceKey("127.0.0.1", 8002)].BusyTime = 5 * time.Second
+				dm.usage[DeviceKey("127.0.0.1", 8003)].BusyTime = time.Second
+			},
+			expected: []int{8003, 8003},
+		},
+		{
+			name:     "LEAST_BUSY ignora las instancias ocupadas",
+			strategy: IoBalanceLeastBusy,
+			prepare: func(dm *DeviceManager) {
+				dm.usage[DeviceKey("127.0.0.1", 8001)].BusyTime = 3 * time.Second
+				dm.usage[DeviceKey("127.0.0.1", 8002)].BusyTime = 5 * time.Second
+				dm.findByAddress("127.0.0.1", 8003).IsFree = false
+			},
+			expected: []int{8001},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dm := newTestDeviceManager(test.strategy, 8001, 8002, 8003)
+			if test.prepare != nil {
+				test.prepare(dm)
+			}
+
+			for i, expected := range test.expected {
+				device, exists := dm.GetFreeByName("DISCO")
+				if !exists || device == nil {
+					t.Fatalf("Reserva %d: expected port %d, got no instance", i, expected)
+				}
+				if device.Port != expected {
+					t.Errorf("Reserva %d: expected port %d, got %d", i, expected, device.Port)
+				}
+				dm.Assign(device, uint(i))
+				dm.Release(device.Ip, device.Port, uint(i))
+			}
+		})
+	}
+}
+
+// El tiempo ocupado se acumula al liberar, así LEAST_BUSY deja de elegir a la instancia que acaba de trabajar.
+func TestDeviceManager_LeastBusyAccumulatesBusyTime(t *testing.T) {
+	dm := newTestDeviceManager(IoBalanceLeastBusy, 8001, 8002)
+
+	device, _ := dm.GetFreeByName("DISCO")
+	if device.Port != 8001 {
+		t.Fatalf("Expected port 8001, got %d", device.Port)
+	}
+	dm.Assign(device, 1)
+	time.Sleep(5 * time.Millisecond)
+	dm.Release(device.Ip, device.Port, 1)
+
+	if dm.usage[DeviceKey("127.0.0.1", 8001)].BusyTime <= 0 {
+		t.Errorf("Expected busy time on port 8001, got %v", dm.usage[DeviceKey("127.0.0.1", 8001)].BusyTime)
+	}
+	if device, _ := dm.GetFreeByName("DISCO"); device.Port != 8002 {
+		t.Errorf("Expected port 8002, got %d", device.Port)
+	}
+}
+
+func TestDeviceManager_GetFreeByNameWithoutFreeInstance(t *testing.T) {
+	dm := newTestDeviceManager(IoBalanceFirstFree, 8001)
+
+	if _, exists := dm.GetFreeByName("CINTA"); exists {
+		t.Errorf("Expected an unknown device to not exist")
+	}
+
+	dm.GetFreeByName("DISCO")
+	device, exists := dm.GetFreeByName("DISCO")
+	if !exists || device != nil {
+		t.Errorf("Expected an existing device without free instances, got %v, %v", device, exists)
+	}
+}
+
+// Dos instancias en hosts distintos pueden escuchar en el mismo puerto: cada una se libera por su ip:puerto.
+func TestDeviceManager_InstancesAreKeyedByAddress(t *testing.T) {
+	dm := NewDeviceManager()
+	dm.Register(&ioModels.Device{Name: "DISCO", Ip: "10.0.0.1", Port: 8001})
+	if registered, _ := dm.Register(&ioModels.Device{Name: "DISCO", Ip: "10.0.0.2", Port: 8001}); !registered {
+		t.Fatalf("Expected a second instance on another host to be registered")
+	}
+
+	first, _ := dm.GetFreeByName("DISCO")
+	dm.Assign(first, 1)
+	second, _ := dm.GetFreeByName("DISCO")
+	dm.Assign(second, 2)
+	if first.Ip == second.Ip {
+		t.Fatalf("Expected both instances to be used, got %s twice", first.Ip)
+	}
+
+	dm.Release(second.Ip, second.Port, 2)
+	if !second.IsFree || first.IsFree {
+		t.Errorf("Expected only %s to be released", second.Ip)
+	}
+	if pids := dm.GetPidsByAddress(first.Ip, first.Port); len(pids) != 1 || pids[0] != 1 {
+		t.Errorf("Expected PID 1 on %s, got %v", first.Ip, pids)
+	}
+
+	dm.RemoveByAddress(first.Ip, first.Port)
+	if _, found := dm.SetDraining(second.Ip, second.Port); !found {
+		t.Errorf("Expected %s to stay registered", second.Ip)
+	}
+}
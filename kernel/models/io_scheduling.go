@@ -0,0 +1,196 @@
+package models
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// Políticas para elegir el próximo proceso de la cola de espera de un dispositivo.
+// El tercer parámetro de la syscall IO es la prioridad (PRIORITY) o la pista pedida (SSTF y SCAN).
+const (
+	IoSchedulingFIFO     = "FIFO"
+	IoSchedulingSRF      = "SRF"      // Shortest request first: el menor tiempo de I/O pedido.
+	IoSchedulingPriority = "PRIORITY" // El menor número es el más prioritario.
+	IoSchedulingSSTF     = "SSTF"     // La pista más cercana a la posición del cabezal.
+	IoSchedulingSCAN     = "SCAN"     // Recorre en un sentido y se invierte en la última pista pedida.
+)
+
+var ioSchedulingPolicies = map[string]bool{
+	IoSchedulingFIFO:     true,
+	IoSchedulingSRF:      true,
+	IoSchedulingPriority: true,
+	IoSchedulingSSTF:     true,
+	IoSchedulingSCAN:     true,
+}
+
+// diskHead es el cabezal simulado de un dispositivo con política SSTF o SCAN.
+type diskHead struct {
+	position  int
+	ascending bool
+}
+
+// Syscalls que mueven datos entre la memoria del proceso y un dispositivo: IO_READ <disp> <dir> <tam> [posición].
+// En DMA_READ y DMA_WRITE, con los mismos parámetros, es el Kernel quien mueve los datos entre Memoria y el dispositivo.
+const (
+	SyscallIoRead   = "IO_READ"
+	SyscallIoWrite  = "IO_WRITE"
+	SyscallDmaRead  = "DMA_READ"
+	SyscallDmaWrite = "DMA_WRITE"
+)
+
+// IsDataIo indica si la syscall mueve datos en lugar de solo esperar un tiempo.
+func IsDataIo(syscallType string) bool {
+	return syscallType == SyscallIoRead || syscallType == SyscallIoWrite || IsDmaIo(syscallType)
+}
+
+// IsDmaIo indica si los datos de la syscall pasan por el Kernel en lugar de que el dispositivo acceda a Memoria.
+func IsDmaIo(syscallType string) bool {
+	return syscallType == SyscallDmaRead || syscallType == SyscallDmaWrite
+}
+
+// IoRequestTime devuelve el tiempo de I/O pedido por la syscall. IO_READ e IO_WRITE no piden tiempo.
+func IoRequestTime(request *SyscallRequest) (int, error) {
+	if IsDataIo(request.Type) {
+		return 0, nil
+	}
+	return strconv.Atoi(request.Values[1])
+}
+
+// IoRequestOffset devuelve la posición en el dispositivo de IO_READ e IO_WRITE, 0 si no se indicó.
+func IoRequestOffset(request *SyscallRequest) int {
+	return intValue(request, 3)
+}
+
+// ioRequestParam devuelve el parámetro opcional de la syscall: la prioridad o pista de IO,
+// o la posición en el dispositivo de IO_READ e IO_WRITE. Es 0 si no se indicó o es inválido.
+func ioRequestParam(request *SyscallRequest) int {
+	if request != nil && IsDataIo(request.Type) {
+		return IoRequestOffset(request)
+	}
+	return intValue(request, 2)
+}
+
+// ioRequestCost es el costo que ordena SRF: el tiempo pedido, o la cantidad de bytes si la syscall mueve datos.
+func ioRequestCost(request *SyscallRequest) int {
+	if request != nil && IsDataIo(request.Type) {
+		return intValue(request, 2)
+	}
+	return intValue(request, 1)
+}
+
+func intValue(request *SyscallRequest, index int) int {
+	if request == nil || len(request.Values) <= index {
+		return 0
+	}
+	value, err := strconv.Atoi(request.Values[index])
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// SetPolicies fija la política de cada dispositivo. Los dispositivos sin política, o con una desconocida, usan FIFO.
+func (wm *WaitingProcessManager) SetPolicies(policies map[string]string) {
+	wm.mx.Lock()
+	defer wm.mx.Unlock()
+	for deviceName, policy := range policies {
+		if !ioSchedulingPolicies[policy] {
+			slog.Warn("Política de planificación de I/O desconocida. Se usa FIFO.", "dispositivo", deviceName, "politica", policy)
+			continue
+		}
+		wm.policies[deviceName] = policy
+	}
+}
+
+// MoveHead lleva el cabezal del dispositivo a la pista de la solicitud que empieza a atenderse.
+func (wm *WaitingProcessManager) MoveHead(deviceName string, request *SyscallRequest) {
+	wm.mx.Lock()
+	defer wm.mx.Unlock()
+	wm.moveHead(deviceName, request)
+}
+
+// moveHead requiere wm.mx tomado.
+func (wm *WaitingProcessManager) moveHead(deviceName string, request *SyscallRequest) {
+	policy := wm.policies[deviceName]
+	if policy != IoSchedulingSSTF && policy != IoSchedulingSCAN {
+		return
+	}
+	head := wm.headFor(deviceName)
+	track := ioRequestParam(request)
+	if track != head.position {
+		head.ascending = track > head.position
+	}
+	head.position = track
+}
+
+func (wm *WaitingProcessManager) headFor(deviceName string) *diskHead {
+	head, exists := wm.heads[deviceName]
+	if !exists {
+		head = &diskHead{ascending: true}
+		wm.heads[deviceName] = head
+	}
+	return head
+}
+
+// selectWaiting devuelve el índice del próximo proceso a atender según la política del dispositivo.
+// Ante un empate gana el que llegó primero. Requiere wm.mx tomado.
+func (wm *WaitingProcessManager) selectWaiting(deviceName string, waiting []*PCB) int {
+	switch wm.policies[deviceName] {
+	case IoSchedulingSRF:
+		return selectMinimumRequest(waiting, ioRequestCost)
+
+	case IoSchedulingPriority:
+		return selectMinimumRequest(waiting, ioRequestParam)
+
+	case IoSchedulingSSTF:
+		head := wm.headFor(deviceName)
+		return selectMinimumRequest(waiting, func(request *SyscallRequest) int {
+			distance := ioRequestParam(request) - head.position
+			if distance < 0 {
+				return -distance
+			}
+			return distance
+		})
+
+	case IoSchedulingSCAN:
+		head := wm.headFor(deviceName)
+		if index := selectInDirection(waiting, head.position, head.ascending); index >= 0 {
+			return index
+		}
+		head.ascending = !head.ascending
+		return selectInDirection(waiting, head.position, head.ascending)
+
+	default:
+		return 0
+	}
+}
+
+func selectMinimumRequest(waiting []*PCB, metric func(*SyscallRequest) int) int {
+	selected, best := 0, 0
+	for index, pcb := range waiting {
+		value := metric(pcb.PendingIoRequest)
+		if index == 0 || value < best {
+			selected, best = index, value
+		}
+	}
+	return selected
+}
+
+// selectInDirection elige la pista pedida más cercana al cabezal en el sentido indicado, -1 si no hay ninguna.
+func selectInDirection(waiting []*PCB, position int, ascending bool) int {
+	selected, best := -1, 0
+	for index, pcb := range waiting {
+		track := ioRequestParam(pcb.PendingIoRequest)
+		distance := track - position
+		if !ascending {
+			distance = -distance
+		}
+		if distance < 0 {
+			continue
+		}
+		if selected < 0 || distance < best {
+			selected, best = index, distance
+		}
+	}
+	return selected
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+)
+
+// ioRequest arma un PCB que espera una syscall IO con el tiempo y el parámetro (prioridad o pista) indicados.
+func ioRequest(pid uint, time int, param int) *PCB {
+	return &PCB{
+		PID: pid,
+		PendingIoRequest: &SyscallRequest{
+			Pid:    pid,
+			Type:   "IO",
+			Values: []string{"DISCO", strconv.Itoa(time), strconv.Itoa(param)},
+		},
+	}
+}
+
+func TestWaitingProcessManager_Dequeue(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		head     int // Pista inicial del cabezal: se mueve atendiendo primero un pedido a esa pista.
+		waiting  []*PCB
+		expected []uint
+	}{
+		{
+			name:     "FIFO respeta el orden de llegada",
+			policy:   IoSchedulingFIFO,
+			waiting:  []*PCB{ioRequest(1, 300, 0), ioRequest(2, 100, 0), ioRequest(3, 200, 0)},
+			expected: []uint{1, 2, 3},
+		},
+		{
+			name:     "SRF atiende primero el menor tiempo",
+			policy:   IoSchedulingSRF,
+			waiting:  []*PCB{ioRequest(1, 300, 0), ioRequest(2, 100, 0), ioRequest(3, 200, 0), ioRequest(4, 100, 0)},
+			expected: []uint{2, 4, 3, 1},
+		},
+		{
+			name:     "PRIORITY atiende primero el menor número",
+			policy:   IoSchedulingPriority,
+			waiting:  []*PCB{ioRequest(1, 100, 3), ioRequest(2, 100, 1), ioRequest(3, 100, 2)},
+			expected: []uint{2, 3, 1},
+		},
+		{
+			name:     "SSTF atiende la pista más cercana al cabezal",
+			policy:   IoSchedulingSSTF,
+			head:     50,
+			waiting:  []*PCB{ioRequest(1, 100, 10), ioRequest(2, 100, 60), ioRequest(3, 100, 45), ioRequest(4, 100, 90)},
+			expected: []uint{3, 2, 4, 1},
+		},
+		{
+			name:     "SCAN sube hasta la última pista pedida y se invierte",
+			policy:   IoSchedulingSCAN,
+			head:     50,
+			waiting:  []*PCB{ioRequest(1, 100, 40), ioRequest(2, 100, 70), ioRequest(3, 100, 10), ioRequest(4, 100, 55)},
+			expected: []uint{4, 2, 1, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wm := NewWaitingProcessManager()
+			wm.SetPolicies(map[string]string{"DISCO": test.policy})
+			if test.head != 0 {
+				wm.MoveHead("DISCO", ioRequest(0, 0, test.head).PendingIoRequest)
+			}
+			for _, pcb := range test.waiting {
+				wm.Enqueue("DISCO", pcb)
+			}
+
+			for i, expected := range test.expected {
+				pcb, ok := wm.Dequeue("DISCO")
+				if !ok {
+					t.Fatalf("Dequeue %d: expected PID %d, got an empty queue", i, expected)
+				}
+				if pcb.PID != expected {
+					t.Errorf("Dequeue %d: expected PID %d, got %d", i, expected, pcb.PID)
+				}
+			}
+			if _, ok := wm.Dequeue("DISCO"); ok {
+				t.Errorf("Expected an empty queue")
+			}
+		})
+	}
+}
+
+// SCAN retoma el sentido descendente mientras queden pistas por debajo, aunque lleguen pedidos por encima.
+func TestWaitingProcessManager_ScanKeepsDirectionAfterReversal(t *testing.T) {
+	wm := NewWaitingProcessManager()
+	wm.SetPolicies(map[string]string{"DISCO": IoSchedulingSCAN})
+	wm.Enqueue("DISCO", ioRequest(1, 100, 80))
+	wm.Dequeue("DISCO")
+	wm.Enqueue("DISCO", ioRequest(2, 100, 30))
+	wm.Dequeue("DISCO")
+
+	// El cabezal bajó de 80 a 30: los pedidos por debajo se atienden antes que los de arriba.
+	wm.Enqueue("DISCO", ioRequest(3, 100, 60))
+	wm.Enqueue("DISCO", ioRequest(4, 100, 20))
+	wm.Enqueue("DISCO", ioRequest(5, 100, 10))
+
+	for _, expected := range []uint{4, 5, 3} {
+		pcb, _ := wm.Dequeue("DISCO")
+		if pcb.PID != expected {
+			t.Errorf("Expected PID %d, got %d", expected, pcb.PID)
+		}
+	}
+}
+
+func TestWaitingProcessManager_DataIoUsesOffsetAndSize(t *testing.T) {
+	dataIo := func(pid uint, size int, offset int) *PCB {
+		return &PCB{
+			PID: pid,
+			PendingIoRequest: &SyscallRequest{
+				Pid:    pid,
+				Type:   SyscallIoRead,
+				Values: []string{"DISCO", "0", strconv.Itoa(size), strconv.Itoa(offset)},
+			},
+		}
+	}
+
+	wm := NewWaitingProcessManager()
+	wm.SetPolicies(map[string]string{"DISCO": IoSchedulingSRF, "CINTA": IoSchedulingSSTF})
+	wm.Enqueue("DISCO", dataIo(1, 64, 0))
+	wm.Enqueue("DISCO", dataIo(2, 16, 0))
+	if pcb, _ := wm.Dequeue("DISCO"); pcb.PID != 2 {
+		t.Errorf("SRF: expected PID 2, got %d", pcb.PID)
+	}
+
+	wm.Enqueue("CINTA", dataIo(3, 16, 500))
+	wm.Enqueue("CINTA", dataIo(4, 16, 20))
+	if pcb, _ := wm.Dequeue("CINTA"); pcb.PID != 4 {
+		t.Errorf("SSTF: expected PID 4, got %d", pcb.PID)
+	}
+}
+
+func TestWaitingProcessManager_UnknownPolicyFallsBackToFifo(t *testing.T) {
+	wm := NewWaitingProcessManager()
+	wm.SetPolicies(map[string]string{"DISCO": "LOOK"})
+	wm.Enqueue("DISCO", ioRequest(1, 300, 9))
+	wm.Enqueue("DISCO", ioRequest(2, 100, 1))
+
+	if pcb, _ := wm.Dequeue("DISCO"); pcb.PID != 1 {
+		t.Errorf("Expected PID 1, got %d", pcb.PID)
+	}
+}
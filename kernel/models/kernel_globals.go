@@ -10,16 +10,30 @@ import (
 // --- Estructura de Configuración ---
 
 type Config struct {
-	IpMemory           string  `json:"ip_memory"`
-	PortMemory         int     `json:"port_memory"`
-	IpKernel           string  `json:"ip_kernel"`
-	PortKernel         int     `json:"port_kernel"`
-	SchedulerAlgorithm string  `json:"scheduler_algorithm"`
-	NewAlgorithm       string  `json:"new_algorithm"`
-	Alpha              float32 `json:"alpha"`
-	InitialEstimate    int     `json:"initial_estimate"`
-	SuspensionTime     int     `json:"suspension_time"`
-	LogLevel           string  `json:"log_level"`
+	IpMemory           string            `json:"ip_memory"`
+	PortMemory         int               `json:"port_memory"`
+	IpKernel           string            `json:"ip_kernel"`
+	PortKernel         int               `json:"port_kernel"`
+	SchedulerAlgorithm string            `json:"scheduler_algorithm"`
+	NewAlgorithm       string            `json:"new_algorithm"`
+	Alpha              float32           `json:"alpha"`
+	InitialEstimate    int               `json:"initial_estimate"`
+	SuspensionTime     int               `json:"suspension_time"`
+	LogLevel           string            `json:"log_level"`
+	CpuHealthCheck     int               `json:"cpu_health_check_interval"`   // ms entre chequeos de CPUs (0 = deshabilitado)
+	IoBalancing        string            `json:"io_balancing"`                // FIRST_FREE, ROUND_ROBIN, LEAST_USED o LEAST_BUSY
+	IoScheduling       map[string]string `json:"io_scheduling"`               // Política de la cola de cada dispositivo: FIFO, SRF, PRIORITY, SSTF o SCAN
+	IoTimeoutFactor    float64           `json:"io_timeout_factor"`           // Plazo de una operación de I/O como múltiplo del tiempo pedido (0 = sin plazo)
+	IoTimeoutMin       int               `json:"io_timeout_min"`              // ms mínimos de plazo, cubre las operaciones con datos
+	IoTimeoutAction    string            `json:"io_timeout_action"`           // EXIT o RETRY
+	IoMaxRetries       int               `json:"io_max_retries"`              // Reintentos con RETRY (por plazo o error) antes de finalizar el proceso
+	IoRequeueOnDrain   bool              `json:"io_requeue_on_drain"`         // Reencolar en otra instancia la I/O que devuelve un dispositivo que se cierra
+	IoErrorAction      string            `json:"io_error_action"`             // EXIT o RETRY ante una operación de I/O informada con error
+	PressurePolicy     string            `json:"memory_pressure_policy"`      // NONE, LARGEST, LONGEST_BLOCKED o PRIORITY: qué procesos suspender cuando otro no entra en memoria
+	PressureReady      bool              `json:"memory_pressure_ready"`       // Si no alcanzan los BLOCKED, suspender también procesos en READY para los que esperan en SUSP_READY
+	PressureMinBlocked int               `json:"memory_pressure_min_blocked"` // ms mínimos en su estado actual para ser suspendido por falta de memoria
+	PressureMaxVictims int               `json:"memory_pressure_max_victims"` // Procesos a suspender como máximo por cada proceso que no entra (0 = sin límite)
+	SwapWorkers        int               `json:"swap_workers"`                // Operaciones de SWAP que se le piden a Memoria a la vez
 }
 
 var KernelConfig *Config
@@ -66,6 +80,12 @@ type PCB struct {
 	SwapRequested    bool // Flag para controlar las solicitudes de SWAP
 	Mutex            sync.Mutex
 	SuspensionTimer  *time.Timer
+	LastCpu          *cpuModels.CpuN // CPU que puede tener páginas o traducciones del proceso tras un desalojo
+	PreviousCpu      string          // Clave de la última CPU donde se despachó (afinidad blanda)
+	HardAffinity     string          // Clave de la única CPU donde puede ejecutar (afinidad estricta), vacía si no tiene
+	IoRetries        int             // Reintentos de la operación de I/O actual por vencimiento de plazo
+	IoPinned         bool            // Tiene una operación de I/O con datos pendiente: no se suspende hasta que termine
+	Reservation      string          // Token de los frames reservados en Memoria para volver de SWAP, vacío si no tiene
 }
 
 // --- Estructuras de Comunicación y Syscalls ---
@@ -80,9 +100,16 @@ const (
 )
 
 type SyscallRequest struct {
-	Pid    uint
-	Type   string
-	Values []string
+	Pid       uint
+	Type      string
+	Values    []string
+	Transfers []IoTransfer // Memoria física de IO_READ e IO_WRITE, ya traducida por la CPU.
+}
+
+// IoTransfer es un tramo de memoria física contiguo, dentro de una única página.
+type IoTransfer struct {
+	PhysicalAddress int
+	Length          int
 }
 
 type PCBExecuteRequest struct {
@@ -90,19 +117,50 @@ type PCBExecuteRequest struct {
 	PC             int
 	StatusCodePCB  StatusCodePCB
 	SyscallRequest SyscallRequest
-	ExecutionTime  float32 `json:"execution_time"`
+	ExecutionTime  float32                 `json:"execution_time"`
+	InterruptType  cpuModels.InterruptType `json:"interrupt_type,omitempty"` // Solo si StatusCodePCB es NeedInterrupt
+	DispatchId     uint64                  `json:"dispatch_id"`              // Identifica el despacho al que corresponde el resultado
 }
 
 type MemoryRequest struct {
-	PID  uint   `json:"pid"`
-	Size int    `json:"size"`
-	Path string `json:"path"`
+	PID   uint   `json:"pid"`
+	Size  int    `json:"size"`
+	Path  string `json:"path"`
+	Token string `json:"token,omitempty"` // Reserva de frames que se confirma con la carga
+}
+
+// AffinityRequest fija la afinidad estricta de un proceso a una CPU (núcleo).
+type AffinityRequest struct {
+	PID   uint `json:"pid"`
+	CpuId int  `json:"cpu_id"`
+	Core  int  `json:"core"`
+}
+
+// AffinityStats resume cuántos despachos aprovecharon la afinidad con la CPU anterior.
+// Un hit es un despacho a la misma CPU donde el proceso ejecutó por última vez.
+type AffinityStats struct {
+	Dispatches     int     `json:"dispatches"`
+	FirstDispatch  int     `json:"first_dispatches"`
+	AffinityHits   int     `json:"affinity_hits"`
+	AffinityMisses int     `json:"affinity_misses"`
+	HitRate        float64 `json:"hit_rate"`
+	HardAffinity   int     `json:"hard_affinity_dispatches"`
 }
 
 // DeviceRequest es la estructura que el Kernel envía a un módulo de I/O.
 type DeviceRequest struct {
 	Pid            uint
 	SuspensionTime int
+	Operation      string       // IO, IO_READ, IO_WRITE, DMA_READ o DMA_WRITE. Vacío equivale a IO.
+	Offset         int          // Posición en el dispositivo para las operaciones con datos.
+	Transfers      []IoTransfer // Memoria del proceso desde o hacia donde se mueven los datos.
+	Size           int          // Bytes a leer del dispositivo en DMA_READ.
+	Data           []byte       // Bytes a escribir en el dispositivo en DMA_WRITE, leídos de Memoria por el Kernel.
+}
+
+// IoCancelRequest pide a un módulo de I/O que descarte la solicitud de un proceso.
+type IoCancelRequest struct {
+	Pid uint `json:"pid"`
 }
 
 // --- Gestores de Recursos ---
@@ -110,6 +168,7 @@ type DeviceRequest struct {
 var ConnectedCpuMap = CpuMap{M: make(map[string]*cpuModels.CpuN)}
 var ConnectedDeviceManager = NewDeviceManager()
 var WaitingForDeviceManager = NewWaitingProcessManager()
+var DeviceAccounting = NewIoAccounting()
 
 // --- Canales de Notificación para Planificadores ---
 
@@ -1,8 +1,11 @@
 package models
 
 import (
-	"strconv"
+	"fmt"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	cpuModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
 	ioModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
@@ -16,17 +19,85 @@ type CpuMap struct {
 	M  map[string]*cpuModels.CpuN
 }
 
+// CpuKey arma la clave de una CPU en el mapa. Cada núcleo de un módulo CPU se registra por separado.
+func CpuKey(cpu *cpuModels.CpuN) string {
+	return fmt.Sprintf("%d-%d", cpu.Id, cpu.Core)
+}
+
 func (sMap *CpuMap) Set(key string, value *cpuModels.CpuN) {
 	sMap.mx.Lock()
 	defer sMap.mx.Unlock()
 	sMap.M[key] = value
 }
 
-func (sMap *CpuMap) GetFirstFree() (*cpuModels.CpuN, bool) {
+// Register agrega el núcleo si no estaba registrado. Un núcleo se identifica por ip:puerto y número de núcleo,
+// así los registros repetidos (reintentos o heartbeats) no crean duplicados ni pisan su estado.
+// Si en la misma dirección había núcleos de otro arranque (otro BootId u otro identificador), el módulo se
+// reinició: se quitan todos y se devuelven, porque los procesos que ejecutaban ahí se perdieron.
+// Devuelve false si el núcleo ya estaba registrado.
+func (sMap *CpuMap) Register(cpu *cpuModels.CpuN) (bool, []*cpuModels.CpuN) {
 	sMap.mx.Lock()
 	defer sMap.mx.Unlock()
-	for _, cpu := range sMap.M {
+	key := CpuKey(cpu)
+	replaced := make([]*cpuModels.CpuN, 0)
+	for existingKey, existing := range sMap.M {
+		if existing.Ip != cpu.Ip || existing.Port != cpu.Port {
+			continue
+		}
+		if existing.Id == cpu.Id && existing.BootId == cpu.BootId {
+			if existingKey == key {
+				return false, nil
+			}
+			// Otro núcleo del mismo arranque.
+			continue
+		}
+		replaced = append(replaced, existing)
+		delete(sMap.M, existingKey)
+	}
+	cpu.IsFree = true
+	cpu.PIDExecuting = 0
+	sMap.M[key] = cpu
+	return true, replaced
+}
+
+// FreeKeys devuelve las claves de las CPUs libres, ordenadas.
+func (sMap *CpuMap) FreeKeys() []string {
+	sMap.mx.Lock()
+	defer sMap.mx.Unlock()
+	keys := make([]string, 0)
+	for key, cpu := range sMap.M {
 		if cpu.IsFree {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ReserveForProcess reserva una CPU libre para un proceso. Con afinidad estricta solo sirve la CPU indicada;
+// si no, se prefiere la CPU anterior (afinidad blanda) y en su defecto la primera libre.
+func (sMap *CpuMap) ReserveForProcess(previous string, required string) (*cpuModels.CpuN, bool) {
+	sMap.mx.Lock()
+	defer sMap.mx.Unlock()
+
+	if required != "" {
+		previous = required
+	}
+	if cpu, ok := sMap.M[previous]; ok && cpu.IsFree {
+		cpu.IsFree = false
+		return cpu, true
+	}
+	if required != "" {
+		return nil, false
+	}
+
+	keys := make([]string, 0, len(sMap.M))
+	for key := range sMap.M {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if cpu := sMap.M[key]; cpu.IsFree {
 			cpu.IsFree = false
 			return cpu, true
 		}
@@ -34,15 +105,64 @@ func (sMap *CpuMap) GetFirstFree() (*cpuModels.CpuN, bool) {
 	return nil, false
 }
 
-func (sMap *CpuMap) MarkAsFree(id int) {
+// Exists indica si hay una CPU conectada con esa clave.
+func (sMap *CpuMap) Exists(key string) bool {
 	sMap.mx.Lock()
 	defer sMap.mx.Unlock()
-	key := strconv.Itoa(id)
-	if cpu, ok := sMap.M[key]; ok {
+	_, ok := sMap.M[key]
+	return ok
+}
+
+func (sMap *CpuMap) MarkAsFree(cpu *cpuModels.CpuN) {
+	sMap.mx.Lock()
+	defer sMap.mx.Unlock()
+	if cpu, ok := sMap.M[CpuKey(cpu)]; ok {
 		cpu.IsFree = true
 	}
 }
 
+// ReleaseProcess desasocia el proceso de la CPU si todavía figura ejecutando ahí.
+// Devuelve false si el proceso ya había sido liberado (por ejemplo, al recuperarlo de una CPU caída).
+func (sMap *CpuMap) ReleaseProcess(cpu *cpuModels.CpuN, pid uint) bool {
+	sMap.mx.Lock()
+	defer sMap.mx.Unlock()
+	if cpu.PIDExecuting != pid {
+		return false
+	}
+	cpu.PIDExecuting = 0
+	return true
+}
+
+// RemoveByAddress quita todos los núcleos de la CPU que escucha en ip:port y los devuelve.
+func (sMap *CpuMap) RemoveByAddress(ip string, port int) []*cpuModels.CpuN {
+	sMap.mx.Lock()
+	defer sMap.mx.Unlock()
+	removed := make([]*cpuModels.CpuN, 0)
+	for key, cpu := range sMap.M {
+		if cpu.Ip == ip && cpu.Port == port {
+			removed = append(removed, cpu)
+			delete(sMap.M, key)
+		}
+	}
+	return removed
+}
+
+// Addresses devuelve las direcciones ip:port de las CPUs conectadas, sin repetir (una por módulo CPU).
+func (sMap *CpuMap) Addresses() []*cpuModels.CpuN {
+	sMap.mx.Lock()
+	defer sMap.mx.Unlock()
+	seen := make(map[string]bool)
+	result := make([]*cpuModels.CpuN, 0)
+	for _, cpu := range sMap.M {
+		address := fmt.Sprintf("%s:%d", cpu.Ip, cpu.Port)
+		if !seen[address] {
+			seen[address] = true
+			result = append(result, cpu)
+		}
+	}
+	return result
+}
+
 func (sMap *CpuMap) GetCPUByPid(pid uint) *cpuModels.CpuN {
 	sMap.mx.Lock()
 	defer sMap.mx.Unlock()
@@ -57,22 +177,70 @@ func (sMap *CpuMap) GetCPUByPid(pid uint) *cpuModels.CpuN {
 // --- Gestor de Dispositivos de I/O ---
 
 type DeviceManager struct {
-	mx      sync.Mutex
-	devices map[string][]*ioModels.Device
+	mx        sync.Mutex
+	devices   map[string][]*ioModels.Device
+	usage     map[string]*DeviceUsage // Por ip:puerto de la instancia (DeviceKey).
+	nextIndex map[string]int          // Próxima instancia a considerar con ROUND_ROBIN.
+	balancing string
 }
 
 func NewDeviceManager() *DeviceManager {
 	return &DeviceManager{
-		devices: make(map[string][]*ioModels.Device),
+		devices:   make(map[string][]*ioModels.Device),
+		usage:     make(map[string]*DeviceUsage),
+		nextIndex: make(map[string]int),
+		balancing: IoBalanceFirstFree,
+	}
+}
+
+// Register agrega la instancia si no estaba registrada. Una instancia se identifica por ip:puerto, así los
+// registros repetidos (reintentos o heartbeats) no crean duplicados ni pisan su estado.
+// Si en la misma dirección había una instancia de otro arranque (otro BootId u otro dispositivo), se reemplaza
+// y se devuelve, porque las operaciones que estaba atendiendo se perdieron.
+// Devuelve false si la instancia ya estaba registrada.
+func (dm *DeviceManager) Register(device *ioModels.Device) (bool, *ioModels.Device) {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	existing := dm.findByAddress(device.Ip, device.Port)
+	if existing != nil {
+		if existing.Name == device.Name && existing.BootId == device.BootId {
+			return false, nil
+		}
+		dm.removeByAddress(device.Ip, device.Port)
 	}
+	dm.add(device)
+	return true, existing
 }
 
+// Add registra una instancia con todos sus slots libres. Una instancia sin capacidad informada atiende de a una operación.
 func (dm *DeviceManager) Add(device *ioModels.Device) {
 	dm.mx.Lock()
 	defer dm.mx.Unlock()
+	dm.add(device)
+}
+
+// add requiere dm.mx tomado.
+func (dm *DeviceManager) add(device *ioModels.Device) {
+	if device.Slots < 1 {
+		device.Slots = 1
+	}
+	device.InUse = 0
+	device.PIDs = nil
+	device.IsFree = true
 	dm.devices[device.Name] = append(dm.devices[device.Name], device)
+	dm.usage[DeviceKey(device.Ip, device.Port)] = &DeviceUsage{ConnectedAt: time.Now()}
 }
 
+// Exists indica si queda al menos una instancia conectada del dispositivo.
+func (dm *DeviceManager) Exists(name string) bool {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	_, exists := dm.devices[name]
+	return exists
+}
+
+// GetFreeByName reserva un slot en una instancia libre del dispositivo según la estrategia de balanceo.
+// Devuelve (nil, true) si el dispositivo existe pero todas sus instancias están ocupadas.
 func (dm *DeviceManager) GetFreeByName(name string) (*ioModels.Device, bool) {
 	dm.mx.Lock()
 	defer dm.mx.Unlock()
@@ -80,54 +248,135 @@ func (dm *DeviceManager) GetFreeByName(name string) (*ioModels.Device, bool) {
 	if !exists {
 		return nil, false
 	}
-	for _, device := range deviceList {
-		if device.IsFree {
-			device.IsFree = false
-			return device, true
+	index := dm.selectInstance(name, deviceList)
+	if index < 0 {
+		return nil, true
+	}
+	device := deviceList[index]
+	device.InUse++
+	device.IsFree = hasFreeSlot(device)
+	return device, true
+}
+
+// CancelReservation devuelve un slot reservado con GetFreeByName que no llegó a asignarse a un proceso.
+func (dm *DeviceManager) CancelReservation(device *ioModels.Device) {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	if device.InUse > len(device.PIDs) {
+		device.InUse--
+		device.IsFree = hasFreeSlot(device)
+	}
+}
+
+// Assign registra que el slot reservado comienza a atender al proceso.
+func (dm *DeviceManager) Assign(device *ioModels.Device, pid uint) {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	if usage, found := dm.usage[DeviceKey(device.Ip, device.Port)]; found {
+		usage.Dispatches++
+		if len(device.PIDs) == 0 {
+			usage.busySince = time.Now()
 		}
 	}
-	return nil, true
+	device.PIDs = append(device.PIDs, pid)
+	DeviceAccounting.StartService(device, pid)
 }
 
-func (dm *DeviceManager) MarkAsFreeByPort(port int) (*ioModels.Device, bool) {
+// Release libera el slot que ocupaba el proceso en la instancia identificada por ip:puerto.
+func (dm *DeviceManager) Release(ip string, port int, pid uint) (*ioModels.Device, bool) {
 	dm.mx.Lock()
 	defer dm.mx.Unlock()
-	for _, deviceList := range dm.devices {
-		for _, device := range deviceList {
-			if device.Port == port {
-				device.IsFree = true
-				device.PID = 0
-				return device, true
-			}
+	device := dm.findByAddress(ip, port)
+	if device == nil {
+		return nil, false
+	}
+	index := slices.Index(device.PIDs, pid)
+	if index < 0 {
+		return device, true
+	}
+	device.PIDs = slices.Delete(device.PIDs, index, index+1)
+	DeviceAccounting.FinishService(device, pid)
+	device.InUse--
+	device.IsFree = hasFreeSlot(device)
+	if usage, found := dm.usage[DeviceKey(ip, port)]; found && len(device.PIDs) == 0 && !usage.busySince.IsZero() {
+		usage.BusyTime += time.Since(usage.busySince)
+		usage.busySince = time.Time{}
+	}
+	return device, true
+}
+
+// SetDraining marca la instancia como en cierre: termina lo que está atendiendo pero no recibe nuevos procesos.
+func (dm *DeviceManager) SetDraining(ip string, port int) (*ioModels.Device, bool) {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	device := dm.findByAddress(ip, port)
+	if device == nil {
+		return nil, false
+	}
+	device.Draining = true
+	device.IsFree = false
+	return device, true
+}
+
+// HasAvailableInstance indica si queda alguna instancia del dispositivo que acepte procesos.
+func (dm *DeviceManager) HasAvailableInstance(name string) bool {
+	dm.mx.Lock()
+	defer dm.mx.Unlock()
+	for _, device := range dm.devices[name] {
+		if !device.Draining {
+			return true
 		}
 	}
-	return nil, false
+	return false
 }
 
-// **NUEVA FUNCIÓN**
-// GetPidByPort devuelve el PID del proceso que se está ejecutando en un dispositivo específico.
-func (dm *DeviceManager) GetPidByPort(port int) uint {
+func hasFreeSlot(device *ioModels.Device) bool {
+	return !device.Draining && device.InUse < device.Slots
+}
+
+// GetPidsByAddress devuelve los procesos que está atendiendo la instancia que escucha en ip:puerto.
+func (dm *DeviceManager) GetPidsByAddress(ip string, port int) []uint {
 	dm.mx.Lock()
 	defer dm.mx.Unlock()
+	if device := dm.findByAddress(ip, port); device != nil {
+		return slices.Clone(device.PIDs)
+	}
+	return nil
+}
+
+// DeviceKey arma la clave de una instancia de dispositivo. Varias instancias pueden usar el mismo puerto
+// en hosts distintos, así que se identifican por ip:puerto.
+func DeviceKey(ip string, port int) string {
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+// findByAddress requiere dm.mx tomado.
+func (dm *DeviceManager) findByAddress(ip string, port int) *ioModels.Device {
 	for _, deviceList := range dm.devices {
 		for _, device := range deviceList {
-			if device.Port == port {
-				return device.PID
+			if device.Ip == ip && device.Port == port {
+				return device
 			}
 		}
 	}
-	return 0 // Retorna 0 si no se encuentra o no hay proceso asignado
+	return nil
 }
 
 // **NUEVA FUNCIÓN**
-// RemoveByPort elimina un dispositivo de la lista de conectados, identificado por su puerto.
-func (dm *DeviceManager) RemoveByPort(port int) {
+// RemoveByAddress elimina un dispositivo de la lista de conectados, identificado por ip:puerto.
+func (dm *DeviceManager) RemoveByAddress(ip string, port int) {
 	dm.mx.Lock()
 	defer dm.mx.Unlock()
+	dm.removeByAddress(ip, port)
+}
+
+// removeByAddress requiere dm.mx tomado.
+func (dm *DeviceManager) removeByAddress(ip string, port int) {
+	delete(dm.usage, DeviceKey(ip, port))
 	for name, deviceList := range dm.devices {
 		newList := []*ioModels.Device{}
 		for _, device := range deviceList {
-			if device.Port != port {
+			if device.Ip != ip || device.Port != port {
 				newList = append(newList, device)
 			}
 		}
@@ -142,13 +391,19 @@ func (dm *DeviceManager) RemoveByPort(port int) {
 // --- Gestor de Procesos en Espera de I/O ---
 
 type WaitingProcessManager struct {
-	mx     sync.Mutex
-	queues map[string]*list.ArrayList[*PCB]
+	mx         sync.Mutex
+	queues     map[string]*list.ArrayList[*PCB]
+	policies   map[string]string    // Política de planificación por dispositivo, FIFO si no figura.
+	heads      map[string]*diskHead // Cabezal simulado de los dispositivos con SSTF o SCAN.
+	enqueuedAt map[uint]time.Time   // Momento en que cada proceso entró a la cola, para medir su espera.
 }
 
 func NewWaitingProcessManager() *WaitingProcessManager {
 	return &WaitingProcessManager{
-		queues: make(map[string]*list.ArrayList[*PCB]),
+		queues:     make(map[string]*list.ArrayList[*PCB]),
+		policies:   make(map[string]string),
+		heads:      make(map[string]*diskHead),
+		enqueuedAt: make(map[uint]time.Time),
 	}
 }
 
@@ -159,6 +414,33 @@ func (wm *WaitingProcessManager) Enqueue(deviceName string, pcb *PCB) {
 		wm.queues[deviceName] = &list.ArrayList[*PCB]{}
 	}
 	wm.queues[deviceName].Add(pcb)
+	wm.enqueuedAt[pcb.PID] = time.Now()
+	DeviceAccounting.RecordQueueLength(deviceName, wm.queues[deviceName].Size())
+}
+
+// Length devuelve la cantidad de procesos esperando por el dispositivo.
+func (wm *WaitingProcessManager) Length(deviceName string) int {
+	wm.mx.Lock()
+	defer wm.mx.Unlock()
+	queue, exists := wm.queues[deviceName]
+	if !exists {
+		return 0
+	}
+	return queue.Size()
+}
+
+// Remove quita al proceso de la cola de espera en la que esté. Devuelve false si no estaba esperando.
+func (wm *WaitingProcessManager) Remove(pid uint) bool {
+	wm.mx.Lock()
+	defer wm.mx.Unlock()
+	for _, queue := range wm.queues {
+		if _, _, found := queue.Find(func(pcb *PCB) bool { return pcb.PID == pid }); found {
+			queue.RemoveWhere(func(pcb *PCB) bool { return pcb.PID == pid })
+			delete(wm.enqueuedAt, pid)
+			return true
+		}
+	}
+	return false
 }
 
 func (wm *WaitingProcessManager) Dequeue(deviceName string) (*PCB, bool) {
@@ -168,9 +450,16 @@ func (wm *WaitingProcessManager) Dequeue(deviceName string) (*PCB, bool) {
 	if !exists || queue.Size() == 0 {
 		return nil, false
 	}
-	pcb, err := queue.Dequeue()
+	index := wm.selectWaiting(deviceName, queue.GetAll())
+	pcb, err := queue.Get(index)
 	if err != nil {
 		return nil, false
 	}
+	queue.Remove(index)
+	if enqueuedAt, found := wm.enqueuedAt[pcb.PID]; found {
+		DeviceAccounting.RecordWait(deviceName, time.Since(enqueuedAt))
+		delete(wm.enqueuedAt, pcb.PID)
+	}
+	wm.moveHead(deviceName, pcb.PendingIoRequest)
 	return pcb, true
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
 )
 
 // CheckUserMemoryCapacity envía una solicitud a Memoria para verificar si hay
@@ -46,3 +47,53 @@ func CheckUserMemoryCapacity(pid uint, processSize int) error {
 	slog.Debug("Memoria confirmó capacidad disponible", "PID", pid)
 	return nil
 }
+
+// ReserveUserMemory le pide a Memoria que aparte los frames del proceso y devuelve el token de la reserva.
+// A diferencia de CheckUserMemoryCapacity, verificar y apartar es una sola operación: el PLP y el PMP no
+// pueden contar los mismos frames libres. La reserva se confirma al cargar el proceso o sacarlo de SWAP.
+func ReserveUserMemory(pid uint, processSize int) (string, error) {
+	body, _ := json.Marshal(struct {
+		PID  uint `json:"pid"`
+		Size int  `json:"size"`
+	}{PID: pid, Size: processSize})
+
+	response, err := client.DoRequest(models.KernelConfig.PortMemory, models.KernelConfig.IpMemory, "POST", "memoria/reservarFrames", body)
+	if err != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+		return "", fmt.Errorf("error al reservar memoria: %w", err)
+	}
+	defer response.Body.Close()
+
+	// Usamos StatusNoContent (204) como señal de "no hay espacio", igual que en capacidadUserMemory.
+	if response.StatusCode == http.StatusNoContent {
+		return "", fmt.Errorf("memoria insuficiente")
+	}
+	var reservation struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&reservation); err != nil {
+		return "", err
+	}
+	slog.Debug("Memoria reservó frames para el proceso", "PID", pid, "token", reservation.Token)
+	return reservation.Token, nil
+}
+
+// ReleaseUserMemory devuelve a Memoria los frames de una reserva que no se va a confirmar.
+func ReleaseUserMemory(pid uint, token string) {
+	if token == "" {
+		return
+	}
+	body, _ := json.Marshal(struct {
+		Token string `json:"token"`
+	}{Token: token})
+
+	response, err := client.DoRequest(models.KernelConfig.PortMemory, models.KernelConfig.IpMemory, "POST", "memoria/liberarReserva", body)
+	if response != nil {
+		response.Body.Close()
+	}
+	if err != nil {
+		slog.Warn("No se pudo liberar la reserva de memoria. Memoria la libera al vencer.", "PID", pid, "token", token, "error", err)
+	}
+}
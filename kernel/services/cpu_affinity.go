@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+)
+
+var (
+	affinityStats kernelModels.AffinityStats
+	affinityMutex sync.Mutex
+)
+
+// canRunOnFreeCpu indica si el proceso puede ejecutar en alguna de las CPUs libres.
+// Solo los procesos con afinidad estricta pueden quedar excluidos.
+func canRunOnFreeCpu(pcb *kernelModels.PCB, freeCpus []string) bool {
+	pcb.Mutex.Lock()
+	hardAffinity := pcb.HardAffinity
+	pcb.Mutex.Unlock()
+	return hardAffinity == "" || slices.Contains(freeCpus, hardAffinity)
+}
+
+// eligibleForFreeCpus devuelve los PIDs en READY que pueden ejecutar en alguna de las CPUs libres.
+func eligibleForFreeCpus(freeCpus []string) map[uint]bool {
+	eligible := make(map[uint]bool)
+	for _, pcb := range kernelModels.QueueReady.GetAll() {
+		if canRunOnFreeCpu(pcb, freeCpus) {
+			eligible[pcb.PID] = true
+		}
+	}
+	return eligible
+}
+
+// recordAffinity registra en qué CPU se despacha el proceso y actualiza las estadísticas.
+func recordAffinity(pcb *kernelModels.PCB, cpu *models.CpuN) {
+	key := kernelModels.CpuKey(cpu)
+
+	pcb.Mutex.Lock()
+	previousCpu, hardAffinity := pcb.PreviousCpu, pcb.HardAffinity
+	pcb.PreviousCpu = key
+	pcb.Mutex.Unlock()
+
+	affinityMutex.Lock()
+	affinityStats.Dispatches++
+	switch {
+	case previousCpu == "":
+		affinityStats.FirstDispatch++
+	case previousCpu == key:
+		affinityStats.AffinityHits++
+	default:
+		affinityStats.AffinityMisses++
+	}
+	if hardAffinity != "" {
+		affinityStats.HardAffinity++
+	}
+	affinityMutex.Unlock()
+
+	if previousCpu != "" && previousCpu != key {
+		slog.Debug("PCP: Proceso despachado en una CPU distinta a la anterior.", "PID", pcb.PID, "cpu_anterior", previousCpu, "cpu", key)
+	}
+}
+
+// GetAffinityStats devuelve las estadísticas de afinidad con la tasa de aciertos calculada.
+func GetAffinityStats() kernelModels.AffinityStats {
+	affinityMutex.Lock()
+	defer affinityMutex.Unlock()
+
+	stats := affinityStats
+	if candidates := stats.AffinityHits + stats.AffinityMisses; candidates > 0 {
+		stats.HitRate = float64(stats.AffinityHits) / float64(candidates)
+	}
+	return stats
+}
+
+// SetHardAffinity fija la CPU en la que debe ejecutar un proceso.
+func SetHardAffinity(request kernelModels.AffinityRequest) error {
+	pcb, found := FindPCBInAnyQueue(request.PID)
+	if !found {
+		return fmt.Errorf("no existe el proceso %d", request.PID)
+	}
+
+	key := kernelModels.CpuKey(&models.CpuN{Id: request.CpuId, Core: request.Core})
+	if !kernelModels.ConnectedCpuMap.Exists(key) {
+		return fmt.Errorf("no hay una CPU conectada con id %d y núcleo %d", request.CpuId, request.Core)
+	}
+
+	pcb.Mutex.Lock()
+	pcb.HardAffinity = key
+	pcb.Mutex.Unlock()
+	slog.Debug("Afinidad estricta asignada.", "PID", request.PID, "cpu", key)
+	return nil
+}
+
+// ClearHardAffinity quita la afinidad estricta de un proceso.
+func ClearHardAffinity(pid uint) error {
+	pcb, found := FindPCBInAnyQueue(pid)
+	if !found {
+		return fmt.Errorf("no existe el proceso %d", pid)
+	}
+
+	pcb.Mutex.Lock()
+	pcb.HardAffinity = ""
+	pcb.Mutex.Unlock()
+	slog.Debug("Afinidad estricta eliminada.", "PID", pid)
+	StartShortTermScheduler()
+	return nil
+}
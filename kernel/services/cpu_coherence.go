@@ -0,0 +1,69 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
+)
+
+// Cuando un proceso es desalojado por una interrupción, la CPU conserva sus páginas en caché
+// (posiblemente modificadas) y sus traducciones en la TLB. Antes de que el proceso se ejecute
+// en otro módulo CPU, o de que Memoria lo mueva a SWAP, se le pide a esa CPU que lo invalide.
+// Los núcleos de un mismo módulo comparten la caché, así que migrar entre ellos no requiere invalidar.
+
+// ensureCpuCoherence invalida el proceso en la CPU anterior si se lo despacha a otro módulo CPU.
+func ensureCpuCoherence(pcb *kernelModels.PCB, cpu *models.CpuN) {
+	lastCpu := takeLastCpu(pcb)
+	if lastCpu == nil || sameCpuModule(lastCpu, cpu) {
+		return
+	}
+	slog.Debug("Proceso migra de CPU. Invalidando caché y TLB en la CPU anterior.", "PID", pcb.PID, "cpu_anterior", kernelModels.CpuKey(lastCpu), "cpu_nueva", kernelModels.CpuKey(cpu))
+	invalidateProcessOnCpu(pcb.PID, lastCpu)
+}
+
+// flushProcessFromCpu invalida el proceso en la última CPU que lo ejecutó, si corresponde.
+func flushProcessFromCpu(pcb *kernelModels.PCB) {
+	lastCpu := takeLastCpu(pcb)
+	if lastCpu == nil {
+		return
+	}
+	slog.Debug("Invalidando caché y TLB del proceso antes de enviarlo a SWAP.", "PID", pcb.PID, "cpu", kernelModels.CpuKey(lastCpu))
+	invalidateProcessOnCpu(pcb.PID, lastCpu)
+}
+
+// sameCpuModule indica si los dos núcleos pertenecen al mismo módulo CPU, identificado por ip:puerto.
+func sameCpuModule(a *models.CpuN, b *models.CpuN) bool {
+	return a.Ip == b.Ip && a.Port == b.Port
+}
+
+// takeLastCpu devuelve la última CPU que ejecutó al proceso y la olvida, bajo el mutex del PCB.
+func takeLastCpu(pcb *kernelModels.PCB) *models.CpuN {
+	pcb.Mutex.Lock()
+	defer pcb.Mutex.Unlock()
+	lastCpu := pcb.LastCpu
+	pcb.LastCpu = nil
+	return lastCpu
+}
+
+// invalidateProcessOnCpu pide a la CPU que escriba las páginas modificadas del proceso y descarte su caché y TLB.
+func invalidateProcessOnCpu(pid uint, cpu *models.CpuN) {
+	if !kernelModels.ConnectedCpuMap.Exists(kernelModels.CpuKey(cpu)) {
+		slog.Debug("La CPU anterior ya no está conectada. No hay nada que invalidar.", "PID", pid, "cpu", kernelModels.CpuKey(cpu))
+		return
+	}
+
+	body, err := json.Marshal(pid)
+	if err != nil {
+		slog.Error("Error al serializar el PID para invalidación.", "error", err)
+		return
+	}
+
+	query := fmt.Sprintf("cpu/invalidate?core=%d", cpu.Core)
+	if _, err := client.DoRequest(cpu.Port, cpu.Ip, "POST", query, body); err != nil {
+		slog.Error("Error enviando la invalidación a la CPU.", "PID", pid, "cpu_id", cpu.Id, "core", cpu.Core, "error", err)
+	}
+}
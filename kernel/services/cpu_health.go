@@ -0,0 +1,185 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
+)
+
+// Cantidad de chequeos consecutivos fallidos para dar de baja una CPU.
+const cpuMaxFailedChecks = 3
+
+var (
+	cpuFailedChecks = make(map[string]int)
+	cpuHealthMutex  sync.Mutex
+)
+
+// StartCpuHealthCheck consulta periódicamente a cada CPU registrada y da de baja las que no responden.
+func StartCpuHealthCheck() {
+	interval := time.Duration(kernelModels.KernelConfig.CpuHealthCheck) * time.Millisecond
+	if interval <= 0 {
+		slog.Debug("Chequeo de salud de CPUs deshabilitado.")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, cpu := range kernelModels.ConnectedCpuMap.Addresses() {
+			go checkCpuHealth(cpu.Ip, cpu.Port, interval)
+		}
+	}
+}
+
+// checkCpuHealth consulta el estado de los núcleos de la CPU y guarda el PC de cada proceso en ejecución.
+// Si no responde dentro del intervalo cuenta como fallido.
+func checkCpuHealth(ip string, port int, timeout time.Duration) {
+	address := fmt.Sprintf("%s:%d", ip, port)
+
+	done := make(chan error, 1)
+	go func() {
+		cores, err := fetchCoreStatus(ip, port)
+		if err == nil {
+			recordCpuProgress(ip, port, cores)
+		}
+		done <- err
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		err = fmt.Errorf("la CPU no respondió en %v", timeout)
+	}
+
+	cpuHealthMutex.Lock()
+	if err == nil {
+		delete(cpuFailedChecks, address)
+		cpuHealthMutex.Unlock()
+		return
+	}
+	cpuFailedChecks[address]++
+	failures := cpuFailedChecks[address]
+	if failures >= cpuMaxFailedChecks {
+		delete(cpuFailedChecks, address)
+	}
+	cpuHealthMutex.Unlock()
+
+	slog.Warn("Chequeo de salud de CPU fallido.", "cpu", address, "fallos", failures, "error", err)
+	if failures >= cpuMaxFailedChecks {
+		DeregisterCpu(ip, port, "no responde a los chequeos de salud")
+	}
+}
+
+// fetchCoreStatus consulta a la CPU qué proceso y PC tiene cada uno de sus núcleos.
+func fetchCoreStatus(ip string, port int) ([]models.CoreStatus, error) {
+	response, err := client.DoRequest(port, ip, "GET", "cpu/status")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var cores []models.CoreStatus
+	if err := json.NewDecoder(response.Body).Decode(&cores); err != nil {
+		return nil, fmt.Errorf("estado de núcleos inválido: %w", err)
+	}
+	return cores, nil
+}
+
+// waitForCoreToFree deja el núcleo ocupado hasta que la CPU informe que no ejecuta ningún proceso, y recién
+// entonces lo libera. Se usa cuando la CPU rechaza un despacho porque el núcleo sigue ejecutando algo que el
+// Kernel no tiene registrado, para no volver a despacharle procesos mientras tanto.
+func waitForCoreToFree(cpu *models.CpuN) {
+	key := kernelModels.CpuKey(cpu)
+	backoff := client.Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second}
+	client.RetryWithBackoff(func() error {
+		if !kernelModels.ConnectedCpuMap.Exists(key) {
+			// La CPU se dio de baja o se reinició: ya no hay nada que liberar.
+			return nil
+		}
+		cores, err := fetchCoreStatus(cpu.Ip, cpu.Port)
+		if err != nil {
+			return err
+		}
+		for _, core := range cores {
+			if core.Core == cpu.Core && core.PID != -1 {
+				return fmt.Errorf("el núcleo %s sigue ejecutando el PID %d", key, core.PID)
+			}
+		}
+		kernelModels.ConnectedCpuMap.MarkAsFree(cpu)
+		slog.Debug("PCP: El núcleo que rechazó el despacho quedó libre.", "cpu_id", cpu.Id, "core", cpu.Core)
+		return nil
+	}, &backoff, nil)
+	StartShortTermScheduler()
+}
+
+// RegisterCpu registra el núcleo en el Kernel. Si la CPU se reinició, recupera los procesos que ejecutaban
+// sus núcleos del arranque anterior. Devuelve false si el núcleo ya estaba registrado.
+func RegisterCpu(cpu *models.CpuN) bool {
+	registered, replaced := kernelModels.ConnectedCpuMap.Register(cpu)
+	if len(replaced) > 0 {
+		slog.Info(fmt.Sprintf("## CPU %s:%d reiniciada, se dan de baja sus núcleos anteriores", cpu.Ip, cpu.Port))
+		recoverProcessesFromCpus(replaced)
+	}
+	return registered
+}
+
+// DeregisterCpu quita del Kernel todos los núcleos de la CPU y recupera los procesos que estaban ejecutando ahí.
+func DeregisterCpu(ip string, port int, reason string) {
+	removed := kernelModels.ConnectedCpuMap.RemoveByAddress(ip, port)
+	if len(removed) == 0 {
+		return
+	}
+	slog.Info(fmt.Sprintf("## CPU %s:%d dada de baja: %s", ip, port, reason))
+	recoverProcessesFromCpus(removed)
+}
+
+// recoverProcessesFromCpus devuelve a READY los procesos que ejecutaban en núcleos ya quitados del Kernel.
+func recoverProcessesFromCpus(removed []*models.CpuN) {
+	for _, cpu := range removed {
+		pid := cpu.PIDExecuting
+		if pid == 0 || !kernelModels.ConnectedCpuMap.ReleaseProcess(cpu, pid) {
+			continue
+		}
+		pcb, found := FindPCBInAnyQueue(pid)
+		if !found {
+			continue
+		}
+		recoverProcessFromCpu(pcb, cpu)
+	}
+	StartShortTermScheduler()
+}
+
+// recoverProcessFromCpu devuelve a READY un proceso cuya CPU dejó de estar disponible.
+// Se reanuda desde el último PC que informó la CPU en el chequeo de salud, o desde el del despacho si
+// no llegó a informar ninguno. Solo se vuelven a ejecutar las instrucciones posteriores a ese chequeo,
+// y lo que estaba en la caché de esa CPU se pierde. Si la CPU solo estaba lenta (un falso positivo del
+// chequeo de salud), el proceso puede llegar a ejecutar en dos CPUs a la vez; por eso se da de baja
+// recién tras varios chequeos fallidos consecutivos.
+func recoverProcessFromCpu(pcb *kernelModels.PCB, cpu *models.CpuN) {
+	key := kernelModels.CpuKey(cpu)
+	pc, reported := dropDispatchesForPid(pcb.PID)
+
+	pcb.Mutex.Lock()
+	if reported {
+		pcb.PC = pc
+	}
+	slog.Warn(fmt.Sprintf("## (<%d>) - Se recupera el proceso de la CPU %s desde el PC %d. Las instrucciones posteriores al último chequeo de salud se vuelven a ejecutar.", pcb.PID, key, pcb.PC))
+	pcb.LastCpu = nil
+	if pcb.PreviousCpu == key {
+		pcb.PreviousCpu = ""
+	}
+	if pcb.HardAffinity == key {
+		slog.Warn("Se elimina la afinidad estricta del proceso con una CPU dada de baja.", "PID", pcb.PID, "cpu", key)
+		pcb.HardAffinity = ""
+	}
+	pcb.Mutex.Unlock()
+
+	TransitionProcessState(pcb, kernelModels.EstadoReady)
+	StartShortTermScheduler()
+}
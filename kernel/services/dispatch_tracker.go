@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
+	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+)
+
+var (
+	ErrStaleDispatch  = errors.New("el resultado no corresponde a un despacho pendiente")
+	errCpuUnreachable = errors.New("no se pudo contactar a la CPU")
+	errCoreBusy       = errors.New("el núcleo ya está ejecutando otro proceso")
+)
+
+// pendingDispatch es un proceso enviado a una CPU cuyo resultado todavía no llegó.
+type pendingDispatch struct {
+	pcb        *kernelModels.PCB
+	cpu        *models.CpuN
+	pc         int  // Último PC que informó la CPU en el chequeo de salud.
+	pcReported bool // Si la CPU ya informó el PC de este despacho.
+}
+
+var (
+	pendingDispatches = make(map[uint64]pendingDispatch)
+	lastDispatchId    uint64
+	dispatchMutex     sync.Mutex
+)
+
+// registerDispatch asigna un identificador al despacho. La CPU lo devuelve junto con el resultado.
+func registerDispatch(pcb *kernelModels.PCB, cpu *models.CpuN) uint64 {
+	dispatchMutex.Lock()
+	defer dispatchMutex.Unlock()
+
+	lastDispatchId++
+	pendingDispatches[lastDispatchId] = pendingDispatch{pcb: pcb, cpu: cpu}
+	return lastDispatchId
+}
+
+// takeDispatch quita el despacho de los pendientes. Devuelve false si ya no estaba pendiente.
+func takeDispatch(dispatchId uint64) (pendingDispatch, bool) {
+	dispatchMutex.Lock()
+	defer dispatchMutex.Unlock()
+
+	dispatch, found := pendingDispatches[dispatchId]
+	if found {
+		delete(pendingDispatches, dispatchId)
+	}
+	return dispatch, found
+}
+
+// takeDispatchForPid es como takeDispatch pero además verifica que el despacho sea del proceso indicado.
+func takeDispatchForPid(dispatchId uint64, pid uint) (pendingDispatch, bool) {
+	dispatchMutex.Lock()
+	defer dispatchMutex.Unlock()
+
+	dispatch, found := pendingDispatches[dispatchId]
+	if !found || dispatch.pcb.PID != pid {
+		return pendingDispatch{}, false
+	}
+	delete(pendingDispatches, dispatchId)
+	return dispatch, true
+}
+
+// recordCpuProgress guarda en los despachos pendientes el PC que informó cada núcleo de la CPU.
+func recordCpuProgress(ip string, port int, cores []models.CoreStatus) {
+	dispatchMutex.Lock()
+	defer dispatchMutex.Unlock()
+
+	for dispatchId, dispatch := range pendingDispatches {
+		if dispatch.cpu.Ip != ip || dispatch.cpu.Port != port {
+			continue
+		}
+		for _, core := range cores {
+			if core.Core == dispatch.cpu.Core && core.PID == int(dispatch.pcb.PID) {
+				dispatch.pc = core.PC
+				dispatch.pcReported = true
+				pendingDispatches[dispatchId] = dispatch
+			}
+		}
+	}
+}
+
+// dropDispatchesForPid descarta los despachos pendientes de un proceso, sus resultados se rechazarán.
+// Devuelve el último PC que informó la CPU para ellos, y false si no informó ninguno.
+func dropDispatchesForPid(pid uint) (int, bool) {
+	dispatchMutex.Lock()
+	defer dispatchMutex.Unlock()
+
+	pc, reported := 0, false
+	for dispatchId, dispatch := range pendingDispatches {
+		if dispatch.pcb.PID == pid {
+			if dispatch.pcReported {
+				pc, reported = dispatch.pc, true
+			}
+			delete(pendingDispatches, dispatchId)
+		}
+	}
+	return pc, reported
+}
@@ -23,6 +23,13 @@ func FinishProcess() {
 
 	slog.Debug("Iniciando finalización del proceso", "PID", pcb.PID)
 
+	// Un proceso finalizado mientras esperaba o usaba un dispositivo no debe seguir ocupándolo.
+	CancelProcessIo(pcb)
+	// Tampoco debe quedar a mitad de un SWAP cuando Memoria libere sus recursos.
+	CancelSwap(pcb.PID)
+	// Si esperaba volver de SWAP, sus frames reservados quedan libres para otros procesos.
+	releaseReservation(pcb)
+
 	// 2. Informa a Memoria que libere los recursos del proceso.
 	bodyRequest, err := json.Marshal(pcb.PID)
 	if err != nil {
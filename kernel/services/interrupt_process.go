@@ -48,7 +48,7 @@ func checkForPreemption(newPcb *kernelModels.PCB) {
 
 		cpu := kernelModels.ConnectedCpuMap.GetCPUByPid(victimPcb.PID)
 		if cpu != nil {
-			SendInterruption(victimPcb.PID, cpu)
+			SendInterruption(victimPcb.PID, cpu, models.InterruptPreempt)
 		} else {
 			slog.Warn("SRT: No se encontró la CPU para el proceso a desalojar.", "PID", victimPcb.PID)
 		}
@@ -57,19 +57,75 @@ func checkForPreemption(newPcb *kernelModels.PCB) {
 	}
 }
 
+// RequestInterruption envía una interrupción del tipo indicado a la CPU que ejecuta el proceso.
+// Devuelve false si el proceso no se encuentra en ejecución.
+func RequestInterruption(pid uint, interruptType models.InterruptType) bool {
+	cpu := kernelModels.ConnectedCpuMap.GetCPUByPid(pid)
+	if cpu == nil {
+		slog.Warn("No se encontró la CPU del proceso a interrumpir.", "PID", pid, "type", interruptType)
+		return false
+	}
+	SendInterruption(pid, cpu, interruptType)
+	return true
+}
+
 // SendInterruption envía una señal de interrupción a una CPU específica.
-func SendInterruption(pid uint, cpu *models.CpuN) {
-	slog.Debug("Enviando interrupción a CPU.", "PID", pid, "cpu_id", cpu.Id)
+func SendInterruption(pid uint, cpu *models.CpuN, interruptType models.InterruptType) {
+	slog.Debug("Enviando interrupción a CPU.", "PID", pid, "type", interruptType, "cpu_id", cpu.Id, "core", cpu.Core)
 
-	bodyRequest, err := json.Marshal(pid)
+	bodyRequest, err := json.Marshal(models.Interrupt{PID: int(pid), Type: interruptType})
 	if err != nil {
-		slog.Error("Error al serializar el PID para interrupción.", "error", err)
+		slog.Error("Error al serializar la interrupción.", "error", err)
 		return
 	}
 
-	_, err = client.DoRequest(cpu.Port, cpu.Ip, "POST", "cpu/interrupt", bodyRequest)
+	query := fmt.Sprintf("cpu/interrupt?core=%d", cpu.Core)
+	_, err = client.DoRequest(cpu.Port, cpu.Ip, "POST", query, bodyRequest)
 	if err != nil {
 		slog.Error("Error enviando la interrupción a la CPU.", "cpu_id", cpu.Id, "error", err)
 	}
-	slog.Info(fmt.Sprintf("## (<%d>) - Desalojado por algoritmo SJF/SRT", pid))
+	if interruptType == models.InterruptPreempt {
+		slog.Info(fmt.Sprintf("## (<%d>) - Desalojado por algoritmo SJF/SRT", pid))
+	}
+}
+
+// handleInterruptedProcess decide el destino de un proceso que la CPU devolvió por una interrupción.
+func handleInterruptedProcess(pcb *kernelModels.PCB, interruptType models.InterruptType) {
+	switch interruptType {
+	case models.InterruptKill:
+		slog.Debug("PCP: Proceso finalizado por interrupción KILL.", "PID", pcb.PID)
+		// Se descartan las páginas y traducciones que el proceso dejó en la CPU.
+		flushProcessFromCpu(pcb)
+		TransitionProcessState(pcb, kernelModels.EstadoExit)
+		StartLongTermScheduler()
+
+	case models.InterruptSuspend:
+		slog.Debug("PCP: Proceso suspendido por interrupción SUSPEND.", "PID", pcb.PID)
+		suspendInterruptedProcess(pcb)
+
+	case models.InterruptQuantum:
+		slog.Info(fmt.Sprintf("## (<%d>) - Desalojado por fin de Quantum", pcb.PID))
+		TransitionProcessState(pcb, kernelModels.EstadoReady)
+		StartShortTermScheduler()
+
+	default:
+		slog.Debug("PCP: CPU devolvió el proceso por desalojo.", "PID", pcb.PID, "type", interruptType)
+		TransitionProcessState(pcb, kernelModels.EstadoReady)
+		StartShortTermScheduler()
+	}
+}
+
+// suspendInterruptedProcess deja en SUSPENDED_READY un proceso desalojado y encola su paso a SWAP.
+func suspendInterruptedProcess(pcb *kernelModels.PCB) {
+	kernelSwapController.pmpMutex.Lock()
+	defer kernelSwapController.pmpMutex.Unlock()
+
+	TransitionProcessState(pcb, kernelModels.EstadoSuspendidoReady)
+	pcb.Mutex.Lock()
+	pcb.SwapRequested = true
+	pcb.Mutex.Unlock()
+	if _, queued := requestSwapIn(pcb); !queued {
+		slog.Error("PCP: No se pudo encolar la suspensión del proceso.", "PID", pcb.PID)
+		StartMediumTermScheduler()
+	}
 }
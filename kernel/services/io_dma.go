@@ -0,0 +1,203 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	memoriaModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/memoria/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
+)
+
+var errInvalidDma = errors.New("dirección o tamaño inválido")
+
+var (
+	memoryPageSize      int // Se pide a Memoria la primera vez que se necesita.
+	memoryPageSizeMutex sync.Mutex
+)
+
+// pinForIo marca al proceso para que no se suspenda mientras tenga una operación de I/O con datos pendiente:
+// el dispositivo, o el Kernel en DMA, lee y escribe los frames que se tradujeron al hacer la syscall, y si el
+// proceso pasara a SWAP esos frames podrían asignarse a otro proceso.
+func pinForIo(pcb *kernelModels.PCB, pinned bool) {
+	pcb.Mutex.Lock()
+	defer pcb.Mutex.Unlock()
+	pcb.IoPinned = pinned
+}
+
+// pinnedForIo indica si el proceso no puede suspenderse por una operación de I/O pendiente.
+// Requiere pcb.Mutex tomado.
+func pinnedForIo(pcb *kernelModels.PCB) bool {
+	return pcb.IoPinned
+}
+
+// prepareDma traduce la memoria del proceso y, para DMA_WRITE, lee de Memoria los datos a enviar al dispositivo.
+func prepareDma(pid uint, syscall *kernelModels.SyscallRequest, request *kernelModels.DeviceRequest) error {
+	transfers, err := translateDma(pid, syscall)
+	if err != nil {
+		return err
+	}
+	if syscall.Type == kernelModels.SyscallDmaRead {
+		for _, transfer := range transfers {
+			request.Size += transfer.Length
+		}
+		return nil
+	}
+
+	for _, transfer := range transfers {
+		content, err := readProcessMemory(pid, transfer)
+		if err != nil {
+			return err
+		}
+		request.Data = append(request.Data, content...)
+	}
+	return nil
+}
+
+// completeDma copia en la memoria del proceso los datos que devolvió el dispositivo en DMA_READ.
+func completeDma(pid uint, syscall *kernelModels.SyscallRequest, data []byte) error {
+	if syscall.Type != kernelModels.SyscallDmaRead {
+		return nil
+	}
+	transfers, err := translateDma(pid, syscall)
+	if err != nil {
+		return err
+	}
+	position := 0
+	for _, transfer := range transfers {
+		if position+transfer.Length > len(data) {
+			return fmt.Errorf("el dispositivo devolvió %d bytes", len(data))
+		}
+		if err := writeProcessMemory(pid, transfer, data[position:position+transfer.Length]); err != nil {
+			return err
+		}
+		position += transfer.Length
+	}
+	return nil
+}
+
+// translateDma traduce la dirección lógica de DMA_READ y DMA_WRITE (<disp> <dir> <tam>) a los tramos
+// de memoria física del proceso, uno por página, consultando la tabla de páginas en Memoria.
+func translateDma(pid uint, syscall *kernelModels.SyscallRequest) ([]kernelModels.IoTransfer, error) {
+	if len(syscall.Values) < 3 {
+		return nil, errInvalidDma
+	}
+	logicalAddress, err := strconv.Atoi(syscall.Values[1])
+	if err != nil || logicalAddress < 0 {
+		return nil, errInvalidDma
+	}
+	size, err := strconv.Atoi(syscall.Values[2])
+	if err != nil || size <= 0 {
+		return nil, errInvalidDma
+	}
+	pageSize, err := getMemoryPageSize()
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []kernelModels.IoTransfer{}
+	for current, remaining := logicalAddress, size; remaining > 0; {
+		offset := current % pageSize
+		length := min(pageSize-offset, remaining)
+		frame, err := searchFrame(pid, current/pageSize)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, kernelModels.IoTransfer{PhysicalAddress: frame*pageSize + offset, Length: length})
+		current += length
+		remaining -= length
+	}
+	return transfers, nil
+}
+
+func getMemoryPageSize() (int, error) {
+	memoryPageSizeMutex.Lock()
+	defer memoryPageSizeMutex.Unlock()
+	if memoryPageSize > 0 {
+		return memoryPageSize, nil
+	}
+
+	response, err := client.DoRequest(kernelModels.KernelConfig.PortMemory, kernelModels.KernelConfig.IpMemory, "GET", "config/memoria")
+	if err != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+		return 0, fmt.Errorf("error al pedir la configuración de Memoria: %w", err)
+	}
+	defer response.Body.Close()
+
+	var memoryConfig struct {
+		PageSize int `json:"page_size"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&memoryConfig); err != nil {
+		return 0, err
+	}
+	if memoryConfig.PageSize <= 0 {
+		return 0, fmt.Errorf("tamaño de página inválido: %d", memoryConfig.PageSize)
+	}
+	memoryPageSize = memoryConfig.PageSize
+	return memoryPageSize, nil
+}
+
+func searchFrame(pid uint, pageNumber int) (int, error) {
+	body, _ := json.Marshal(struct {
+		PID        uint `json:"pid"`
+		PageNumber int  `json:"pageNumber"`
+	}{PID: pid, PageNumber: pageNumber})
+	response, err := client.DoRequest(kernelModels.KernelConfig.PortMemory, kernelModels.KernelConfig.IpMemory, "POST", "memoria/buscarFrame", body)
+	if err != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+		return -1, fmt.Errorf("error al buscar el marco en Memoria: %w", err)
+	}
+	defer response.Body.Close()
+
+	var frameResponse struct {
+		Frame int `json:"frame"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&frameResponse); err != nil {
+		return -1, err
+	}
+	if frameResponse.Frame < 0 {
+		return -1, fmt.Errorf("la página %d no tiene marco asignado", pageNumber)
+	}
+	return frameResponse.Frame, nil
+}
+
+func readProcessMemory(pid uint, transfer kernelModels.IoTransfer) ([]byte, error) {
+	body, _ := json.Marshal(memoriaModel.ReadRequest{Pid: pid, PhysicalAddress: transfer.PhysicalAddress, Size: transfer.Length})
+	response, err := client.DoRequest(kernelModels.KernelConfig.PortMemory, kernelModels.KernelConfig.IpMemory, "POST", "memoria/leerMemoria", body)
+	if err != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+		return nil, fmt.Errorf("error al leer desde Memoria: %w", err)
+	}
+	defer response.Body.Close()
+
+	var readResponse struct {
+		Content []byte `json:"content"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&readResponse); err != nil {
+		return nil, err
+	}
+	return readResponse.Content, nil
+}
+
+func writeProcessMemory(pid uint, transfer kernelModels.IoTransfer, data []byte) error {
+	body, _ := json.Marshal(memoriaModel.WriteRequest{Pid: pid, PhysicalAddress: transfer.PhysicalAddress, Data: data})
+	response, err := client.DoRequest(kernelModels.KernelConfig.PortMemory, kernelModels.KernelConfig.IpMemory, "POST", "memoria/write", body)
+	if response != nil {
+		response.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("error al escribir en Memoria: %w", err)
+	}
+	slog.Debug("Datos de DMA copiados a Memoria", "PID", pid, "direccion", transfer.PhysicalAddress, "tamaño", transfer.Length)
+	return nil
+}
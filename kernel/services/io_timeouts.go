@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	ioModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
+	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
+)
+
+// Acciones ante una operación de I/O que excede su plazo o que el dispositivo informa con error.
+const (
+	IoActionExit  = "EXIT"  // Se finaliza el proceso.
+	IoActionRetry = "RETRY" // Se vuelve a encolar la solicitud hasta io_max_retries veces.
+)
+
+// ioInFlight es una solicitud enviada a un dispositivo cuyo fin todavía no se informó.
+type ioInFlight struct {
+	pcb     *kernelModels.PCB
+	device  *ioModel.Device
+	request *kernelModels.SyscallRequest
+	timer   *time.Timer
+}
+
+var (
+	inFlightIo      = make(map[uint]*ioInFlight) // Por PID, un proceso tiene a lo sumo una operación de I/O.
+	inFlightIoMutex sync.Mutex
+)
+
+// ioDeadline calcula el plazo de una solicitud como múltiplo del tiempo pedido, con un mínimo para las
+// operaciones con datos. Devuelve 0 si los plazos están deshabilitados.
+func ioDeadline(request *kernelModels.SyscallRequest) time.Duration {
+	factor := kernelModels.KernelConfig.IoTimeoutFactor
+	if factor <= 0 {
+		return 0
+	}
+	requested, _ := kernelModels.IoRequestTime(request)
+	deadline := time.Duration(factor*float64(requested)) * time.Millisecond
+	if minimum := time.Duration(kernelModels.KernelConfig.IoTimeoutMin) * time.Millisecond; deadline < minimum {
+		deadline = minimum
+	}
+	return deadline
+}
+
+// trackIo registra la solicitud enviada al dispositivo y arranca su plazo.
+func trackIo(pcb *kernelModels.PCB, device *ioModel.Device, request *kernelModels.SyscallRequest) {
+	entry := &ioInFlight{pcb: pcb, device: device, request: request}
+
+	inFlightIoMutex.Lock()
+	defer inFlightIoMutex.Unlock()
+	if deadline := ioDeadline(request); deadline > 0 {
+		entry.timer = time.AfterFunc(deadline, func() { handleIoTimeout(pcb.PID, entry) })
+	}
+	inFlightIo[pcb.PID] = entry
+}
+
+// takeIo quita la solicitud en curso del proceso y detiene su plazo.
+func takeIo(pid uint) (*ioInFlight, bool) {
+	inFlightIoMutex.Lock()
+	defer inFlightIoMutex.Unlock()
+	entry, found := inFlightIo[pid]
+	if !found {
+		return nil, false
+	}
+	delete(inFlightIo, pid)
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+	return entry, true
+}
+
+// CompleteIo registra el fin informado por el dispositivo y, en DMA_READ, copia los datos leídos a la memoria
+// del proceso. Devuelve false si la solicitud ya no estaba en curso porque venció o se canceló; en ese caso
+// el fin se ignora.
+func CompleteIo(pid uint, data []byte) (bool, error) {
+	entry, found := takeIo(pid)
+	if !found {
+		return false, nil
+	}
+	entry.pcb.Mutex.Lock()
+	entry.pcb.IoRetries = 0
+	entry.pcb.Mutex.Unlock()
+	if !kernelModels.IsDmaIo(entry.request.Type) {
+		pinForIo(entry.pcb, false)
+		return true, nil
+	}
+	err := completeDma(pid, entry.request, data)
+	pinForIo(entry.pcb, false)
+	return true, err
+}
+
+// handleIoTimeout cancela la solicitud vencida y reintenta o finaliza el proceso según io_timeout_action.
+func handleIoTimeout(pid uint, expired *ioInFlight) {
+	inFlightIoMutex.Lock()
+	entry, found := inFlightIo[pid]
+	if !found || entry != expired {
+		// El fin llegó justo antes que el plazo.
+		inFlightIoMutex.Unlock()
+		return
+	}
+	delete(inFlightIo, pid)
+	inFlightIoMutex.Unlock()
+
+	slog.Warn(fmt.Sprintf("## (<%d>) - La operación de I/O en <%s> excedió su plazo", pid, entry.device.Name))
+	go sendIoCancel(entry.device, pid)
+	kernelModels.ConnectedDeviceManager.Release(entry.device.Ip, entry.device.Port, pid)
+	retryOrExit(entry, kernelModels.KernelConfig.IoTimeoutAction)
+}
+
+// HandleFailedIo resuelve una operación que el dispositivo informó con error, según io_error_action.
+func HandleFailedIo(pid uint) {
+	entry, found := takeIo(pid)
+	if !found {
+		return
+	}
+	slog.Warn(fmt.Sprintf("## (<%d>) - La operación de I/O en <%s> falló", pid, entry.device.Name))
+	kernelModels.ConnectedDeviceManager.Release(entry.device.Ip, entry.device.Port, pid)
+	retryOrExit(entry, kernelModels.KernelConfig.IoErrorAction)
+}
+
+// retryOrExit reencola la solicitud si la acción es RETRY y quedan reintentos; si no, finaliza el proceso.
+// Al reencolar el proceso sigue fijado: la solicitud reintentada usa los mismos frames.
+func retryOrExit(entry *ioInFlight, action string) {
+	pcb := entry.pcb
+	deviceName := entry.device.Name
+
+	pcb.Mutex.Lock()
+	retry := action == IoActionRetry && pcb.IoRetries < kernelModels.KernelConfig.IoMaxRetries
+	if retry {
+		pcb.IoRetries++
+	}
+	attempt := pcb.IoRetries
+	pcb.Mutex.Unlock()
+
+	if retry {
+		slog.Debug("Reintentando la operación de I/O.", "PID", pcb.PID, "dispositivo", deviceName, "intento", attempt)
+		requeueIo(entry)
+	} else {
+		slog.Debug("Finalizando proceso por operación de I/O fallida.", "PID", pcb.PID, "dispositivo", deviceName)
+		pinForIo(pcb, false)
+		TransitionProcessState(pcb, kernelModels.EstadoExit)
+		StartLongTermScheduler()
+	}
+
+	TryToDispatchNextIO(deviceName)
+}
+
+// HandleInterruptedIo resuelve una solicitud que el dispositivo devolvió sin atender porque se está cerrando.
+// Con io_requeue_on_drain el proceso espera otra instancia del dispositivo; si no, o si no quedan instancias, finaliza.
+func HandleInterruptedIo(pid uint) {
+	entry, found := takeIo(pid)
+	if !found {
+		return
+	}
+	deviceName := entry.device.Name
+	kernelModels.ConnectedDeviceManager.Release(entry.device.Ip, entry.device.Port, pid)
+
+	if kernelModels.KernelConfig.IoRequeueOnDrain && kernelModels.ConnectedDeviceManager.HasAvailableInstance(deviceName) {
+		slog.Info(fmt.Sprintf("## (<%d>) - IO interrumpida en <%s>, se reencola en otra instancia", pid, deviceName))
+		requeueIo(entry)
+		TryToDispatchNextIO(deviceName)
+		return
+	}
+
+	slog.Info(fmt.Sprintf("## (<%d>) - IO interrumpida en <%s>, se finaliza el proceso", pid, deviceName))
+	pinForIo(entry.pcb, false)
+	TransitionProcessState(entry.pcb, kernelModels.EstadoExit)
+	StartLongTermScheduler()
+}
+
+// requeueIo vuelve a poner al proceso en la cola de espera del dispositivo con la misma solicitud.
+func requeueIo(entry *ioInFlight) {
+	entry.pcb.PendingIoRequest = entry.request
+	kernelModels.WaitingForDeviceManager.Enqueue(entry.device.Name, entry.pcb)
+}
+
+// CancelProcessIo descarta cualquier operación de I/O del proceso: lo saca de las colas de espera y, si un
+// dispositivo lo estaba atendiendo, le pide que cancele y libera el slot.
+func CancelProcessIo(pcb *kernelModels.PCB) {
+	pid := pcb.PID
+	pinForIo(pcb, false)
+	if kernelModels.WaitingForDeviceManager.Remove(pid) {
+		slog.Debug("Proceso quitado de la cola de espera de I/O.", "PID", pid)
+	}
+
+	entry, found := takeIo(pid)
+	if !found {
+		return
+	}
+	slog.Debug("Cancelando operación de I/O en curso.", "PID", pid, "dispositivo", entry.device.Name)
+	go sendIoCancel(entry.device, pid)
+	if _, connected := kernelModels.ConnectedDeviceManager.Release(entry.device.Ip, entry.device.Port, pid); connected {
+		TryToDispatchNextIO(entry.device.Name)
+	}
+}
+
+func sendIoCancel(device *ioModel.Device, pid uint) {
+	body, _ := json.Marshal(kernelModels.IoCancelRequest{Pid: pid})
+	resp, err := client.DoRequest(device.Port, device.Ip, "POST", "io/cancel", body)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		slog.Debug("El dispositivo no aceptó la cancelación.", "dispositivo", device.Name, "PID", pid, "error", err)
+	}
+}
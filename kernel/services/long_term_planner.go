@@ -116,22 +116,25 @@ func admitProcess(pcb *models.PCB) bool {
 		return false
 	}
 
-	err := CheckUserMemoryCapacity(pcb.PID, pcb.Size)
+	token, err := ReserveUserMemory(pcb.PID, pcb.Size)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Memoria no tiene espacio para PID %d (tamaño %d). Permanece en NEW.", pcb.PID, pcb.Size))
+		slog.Debug(fmt.Sprintf("Memoria no tiene espacio para PID %d (tamaño %d). Permanece en NEW.", pcb.PID, pcb.Size), "error", err)
+		relieveMemoryPressure(pcb.PID, pcb.Size)
 		return false
 	}
 
 	memRequest := models.MemoryRequest{
-		PID:  pcb.PID,
-		Size: pcb.Size,
-		Path: pcb.PseudocodePath,
+		PID:   pcb.PID,
+		Size:  pcb.Size,
+		Path:  pcb.PseudocodePath,
+		Token: token,
 	}
 	body, _ := json.Marshal(memRequest)
 
 	_, err = client.DoRequest(models.KernelConfig.PortMemory, models.KernelConfig.IpMemory, "POST", "memoria/cargarpcb", body)
 	if err != nil {
 		slog.Error("Fallo la solicitud a Memoria para cargar el PCB.", "PID", pcb.PID, "error", err)
+		ReleaseUserMemory(pcb.PID, token)
 		return false
 	}
 
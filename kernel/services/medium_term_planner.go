@@ -2,62 +2,16 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"sort"
-	"sync"
 
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/web/client"
 )
 
-type KernelSwapController struct {
-	mutex            sync.RWMutex
-	activeOperations map[uint]string // PID -> tipo de operación ("swap_in" o "swap_out")
-	pmpMutex         sync.Mutex      // Mutex para el PMP
-}
-
-var kernelSwapController = &KernelSwapController{
-	activeOperations: make(map[uint]string),
-}
-
-func canProcessSwap(pid uint, operation string) bool {
-	kernelSwapController.mutex.RLock()
-	defer kernelSwapController.mutex.RUnlock()
-
-	currentOp, exists := kernelSwapController.activeOperations[pid]
-	if exists {
-		slog.Debug("Operación ya activa para proceso", "PID", pid, "current_operation", currentOp, "requested_operation", operation)
-		return false
-	}
-	return true
-}
-
-func registerKernelSwapOperation(pid uint, operation string) bool {
-	kernelSwapController.mutex.Lock()
-	defer kernelSwapController.mutex.Unlock()
-
-	if _, exists := kernelSwapController.activeOperations[pid]; exists {
-		return false
-	}
-
-	kernelSwapController.activeOperations[pid] = operation
-	slog.Debug("Operación de kernel registrada", "PID", pid, "operation", operation)
-	return true
-}
-
-func unregisterKernelSwapOperation(pid uint) {
-	kernelSwapController.mutex.Lock()
-	defer kernelSwapController.mutex.Unlock()
-
-	operation, exists := kernelSwapController.activeOperations[pid]
-	if exists {
-		delete(kernelSwapController.activeOperations, pid)
-		slog.Debug("Operación de kernel completada", "PID", pid, "operation", operation)
-	}
-}
-
 // --- Funciones del Planificador ---
 
 func StartMediumTermScheduler() {
@@ -87,48 +41,71 @@ func MediumTermScheduler() {
 
 // --- Lógica de Suspensión (SWAP-IN) ---
 
+// handleSuspendedBlocked encola el SWAP de los procesos en SUSPEND_BLOCKED que todavía no lo pidieron.
 func handleSuspendedBlocked() {
-	var pcbToSwap *models.PCB = nil
-
-	// Buscamos un proceso que necesite ser swapeado sin quitarlo de la cola.
-	allSuspended := models.QueueSuspBlocked.GetAll()
-
-	for _, pcb := range allSuspended {
+	requested := 0
+	for _, pcb := range models.QueueSuspBlocked.GetAll() {
 		pcb.Mutex.Lock()
-		if !pcb.SwapRequested && canProcessSwap(pcb.PID, "swap_in") {
-			_, _, stillInQueue := models.QueueSuspBlocked.Find(func(p *models.PCB) bool {
-				return p.PID == pcb.PID
-			})
-			if stillInQueue {
-				pcb.SwapRequested = true // Lo marcamos para que no se vuelva a procesar
-				pcbToSwap = pcb
-				pcb.Mutex.Unlock()
-				break
-			}
+		eligible := !pcb.SwapRequested && pcb.EstadoActual == models.EstadoSuspendidoBlocked
+		if eligible {
+			pcb.SwapRequested = true // Lo marcamos para que no se vuelva a procesar
 		}
 		pcb.Mutex.Unlock()
+
+		if eligible {
+			if _, queued := requestSwapIn(pcb); queued {
+				requested++
+			}
+		}
 	}
 
-	if pcbToSwap == nil {
+	if requested == 0 {
 		slog.Debug("PMP: No hay nuevos procesos en SUSPEND_BLOCKED para solicitar swap.")
-		return
 	}
+}
 
-	if err := requestSwapIn(pcbToSwap); err != nil {
-		slog.Error("PMP: Error en swap in", "PID", pcbToSwap.PID, "error", err)
-		// Limpiar el flag en caso de error
-		pcbToSwap.Mutex.Lock()
-		pcbToSwap.SwapRequested = false
-		pcbToSwap.Mutex.Unlock()
+// requestSwapIn encola el paso del proceso a SWAP. Quien lo llama ya marcó SwapRequested.
+// Si Memoria no puede swapearlo, sus páginas siguen en memoria: un proceso en SUSP_READY vuelve directo a READY,
+// porque desuspenderlo le pediría a Memoria sacar de SWAP algo que no está, y uno en SUSP_BLOCKED queda sin la
+// marca para que el PMP lo vuelva a intentar. El canal devuelto se cierra cuando Memoria responde.
+func requestSwapIn(pcb *models.PCB) (<-chan struct{}, bool) {
+	done, queued := enqueueSwap(pcb, swapOperationIn, func(err error) {
+		if err != nil {
+			slog.Error("PMP: Error en swap in", "PID", pcb.PID, "error", err)
+			// Bajo pmpMutex, así el PMP no lo desuspende mientras se decide.
+			kernelSwapController.pmpMutex.Lock()
+			pcb.Mutex.Lock()
+			pcb.SwapRequested = false
+			backToReady := pcb.EstadoActual == models.EstadoSuspendidoReady
+			pcb.Mutex.Unlock()
+			if backToReady {
+				slog.Debug("PMP: El proceso no llegó a SWAP, vuelve a READY.", "PID", pcb.PID)
+				TransitionProcessState(pcb, models.EstadoReady)
+			}
+			kernelSwapController.pmpMutex.Unlock()
+
+			if backToReady {
+				StartShortTermScheduler()
+			}
+			StartMediumTermScheduler()
+			return
+		}
+		slog.Debug("PMP: Memoria confirmó SWAP IN exitosamente.", "PID", pcb.PID)
+		StartMediumTermScheduler()
+		StartLongTermScheduler()
+	})
+	if !queued {
+		pcb.Mutex.Lock()
+		pcb.SwapRequested = false
+		pcb.Mutex.Unlock()
 	}
+	return done, queued
 }
 
-func requestSwapIn(pcb *models.PCB) error {
-	// Registrar operación para evitar concurrencia
-	if !registerKernelSwapOperation(pcb.PID, "swap_in") {
-		return fmt.Errorf("operación ya activa para PID %d", pcb.PID)
-	}
-	defer unregisterKernelSwapOperation(pcb.PID)
+// putSwap le pide a Memoria que mueva el proceso a SWAP. La llaman los workers de la cola de SWAP.
+func putSwap(pcb *models.PCB) error {
+	// Las páginas modificadas en la caché de la CPU deben llegar a Memoria antes del SWAP.
+	flushProcessFromCpu(pcb)
 
 	slog.Debug("PMP: Solicitando a Memoria mover proceso a SWAP.", "PID", pcb.PID)
 
@@ -151,8 +128,7 @@ func requestSwapIn(pcb *models.PCB) error {
 		return fmt.Errorf("respuesta nula de memoria")
 	}
 
-	slog.Debug("PMP: Memoria confirmó SWAP IN exitosamente.", "PID", pcb.PID)
-	StartLongTermScheduler()
+	response.Body.Close()
 	return nil
 }
 
@@ -161,12 +137,7 @@ func requestSwapIn(pcb *models.PCB) error {
 func handleSuspendedReady() {
 	switch models.KernelConfig.NewAlgorithm {
 	case "FIFO":
-		if models.QueueSuspReady.Size() > 0 {
-			pcb, err := models.QueueSuspReady.Get(0)
-			if err != nil {
-				slog.Error("Error obteniendo proceso de cola SUSP_READY", "error", err)
-				return
-			}
+		if pcb := firstWithoutSwapJob(models.QueueSuspReady.GetAll()); pcb != nil {
 			desuspendProcess(pcb)
 		}
 	case "PMCP":
@@ -188,12 +159,7 @@ func handleSuspendedReady() {
 		//}
 		scheduleReadyPMCP()
 	default:
-		if models.QueueSuspReady.Size() > 0 {
-			pcb, err := models.QueueSuspReady.Get(0)
-			if err != nil {
-				slog.Error("Error obteniendo proceso de cola SUSP_READY", "error", err)
-				return
-			}
+		if pcb := firstWithoutSwapJob(models.QueueSuspReady.GetAll()); pcb != nil {
 			desuspendProcess(pcb)
 		}
 	}
@@ -209,13 +175,25 @@ func scheduleReadyPMCP() {
 		return allSuspended[i].Size < allSuspended[j].Size
 	})
 
-	pcb := allSuspended[0]
-	desuspendProcess(pcb)
+	if pcb := firstWithoutSwapJob(allSuspended); pcb != nil {
+		desuspendProcess(pcb)
+	}
+}
+
+// firstWithoutSwapJob devuelve el primer proceso que no está yendo a SWAP, o nil si no hay ninguno.
+func firstWithoutSwapJob(pcbs []*models.PCB) *models.PCB {
+	for _, pcb := range pcbs {
+		if !hasSwapJob(pcb.PID) {
+			return pcb
+		}
+	}
+	return nil
 }
 
 func desuspendProcess(pcb *models.PCB) {
-	if !canProcessSwap(pcb.PID, "swap_out") {
+	if hasSwapJob(pcb.PID) {
 		slog.Debug("PMP: Proceso ya siendo procesado para swap out", "PID", pcb.PID)
+		return
 	}
 	slog.Debug("Desuspendiendo proceso para pasar a ready")
 	_, _, found := models.QueueSuspReady.Find(func(p *models.PCB) bool { return p.PID == pcb.PID })
@@ -229,6 +207,7 @@ func desuspendProcess(pcb *models.PCB) {
 		// Verificar si hay capacidad de memoria (por las dudas)
 		if err := CheckUserMemoryCapacity(pcb.PID, pcb.Size); err != nil {
 			slog.Debug("PMP: No hay memoria suficiente para proceso no swapeado. Permanece en SUSP_READY.", "PID", pcb.PID)
+			relieveMemoryPressure(pcb.PID, pcb.Size)
 			return
 		}
 
@@ -256,49 +235,94 @@ func desuspendProcess(pcb *models.PCB) {
 		return
 	}
 
+	// Los frames se reservan recién cuando un worker de SWAP toma la operación (ver removeSwap): la reserva
+	// vence, y Memoria atiende los SWAP de a uno. Acá solo se evita encolar lo que no va a entrar.
 	if err := CheckUserMemoryCapacity(pcb.PID, pcb.Size); err != nil {
-		slog.Debug("PMP: No hay memoria para desuspender proceso. Permanece en SUSP_READY.", "PID", pcb.PID)
+		slog.Debug("PMP: No hay memoria para desuspender proceso. Permanece en SUSP_READY.", "PID", pcb.PID, "error", err)
+		relieveMemoryPressure(pcb.PID, pcb.Size)
 		return
 	}
-
 	requestSwapOut(pcb)
 }
 
-func requestSwapOut(pcb *models.PCB) {
-	if !registerKernelSwapOperation(pcb.PID, "swap_out") {
-		return
+// errNoMemoryForSwapOut indica que al tomar la operación ya no había frames para traer al proceso de SWAP.
+var errNoMemoryForSwapOut = errors.New("memoria insuficiente para sacar el proceso de SWAP")
+
+// requestSwapOut encola el regreso del proceso desde SWAP. Cuando Memoria lo confirma pasa a READY.
+// Devuelve false si el proceso ya tenía una operación de SWAP.
+func requestSwapOut(pcb *models.PCB) bool {
+	_, queued := enqueueSwap(pcb, swapOperationOut, func(err error) {
+		// Reiniciamos el flag para que pueda volver a ser suspendido en el futuro.
+		pcb.Mutex.Lock()
+		pcb.SwapRequested = false
+		state := pcb.EstadoActual
+		pcb.Mutex.Unlock()
+		defer StartMediumTermScheduler()
+
+		if state != models.EstadoSuspendidoReady {
+			return // Finalizó mientras volvía de SWAP.
+		}
+		if errors.Is(err, errNoMemoryForSwapOut) {
+			// Sigue en SWAP: espera en SUSP_READY a que se libere memoria, como si no se hubiera encolado.
+			slog.Debug("PMP: No hay memoria para desuspender proceso. Permanece en SUSP_READY.", "PID", pcb.PID)
+			relieveMemoryPressure(pcb.PID, pcb.Size)
+			return
+		}
+		if err != nil {
+			slog.Error("PMP: Error al solicitar SWAP OUT a Memoria. Finalizando proceso.", "PID", pcb.PID, "error", err)
+			TransitionProcessState(pcb, models.EstadoExit)
+			StartLongTermScheduler()
+			return
+		}
+		slog.Debug(fmt.Sprintf("## (%d) - Pasa de SUSPENDED_READY a READY", pcb.PID))
+		TransitionProcessState(pcb, models.EstadoReady)
+		StartShortTermScheduler()
+	})
+	return queued
+}
+
+// removeSwap le pide a Memoria que traiga el proceso desde SWAP. La llaman los workers de la cola de SWAP.
+// Los frames se reservan al tomar la operación, así el vencimiento de la reserva no corre mientras espera
+// en la cola. Devuelve errNoMemoryForSwapOut si ya no entran.
+func removeSwap(pcb *models.PCB) error {
+	token, err := ReserveUserMemory(pcb.PID, pcb.Size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNoMemoryForSwapOut, err)
 	}
-	defer unregisterKernelSwapOperation(pcb.PID)
+	pcb.Mutex.Lock()
+	pcb.Reservation = token
+	pcb.Mutex.Unlock()
+
 	slog.Debug("PMP: Solicitando a Memoria SWAP OUT.", "PID", pcb.PID)
 	req := struct {
-		PID uint `json:"pid"`
-	}{PID: pcb.PID}
+		PID   uint   `json:"pid"`
+		Token string `json:"token,omitempty"`
+	}{PID: pcb.PID, Token: token}
 	body, _ := json.Marshal(req)
 
 	response, err := client.DoRequest(models.KernelConfig.PortMemory, models.KernelConfig.IpMemory, "POST", "memoria/removeSwap", body)
-
 	if err != nil {
-		slog.Error("PMP: Error al solicitar SWAP OUT a Memoria. Finalizando proceso.", "PID", pcb.PID, "error", err)
-		TransitionProcessState(pcb, models.EstadoExit)
-		StartLongTermScheduler()
-		return
+		// Memoria no llegó a confirmar la reserva: se devuelven sus frames.
+		releaseReservation(pcb)
+		return err
 	}
-
+	pcb.Mutex.Lock()
+	pcb.Reservation = ""
+	pcb.Mutex.Unlock()
 	if response == nil {
-		slog.Error("PMP: Respuesta nula de Memoria para SWAP OUT.", "PID", pcb.PID)
-		TransitionProcessState(pcb, models.EstadoExit)
-		StartLongTermScheduler()
-		return
+		return fmt.Errorf("respuesta nula de memoria")
 	}
+	response.Body.Close()
+	return nil
+}
 
-	// Reiniciamos el flag para que pueda volver a ser suspendido en el futuro.
+// releaseReservation libera la reserva de frames del proceso que no llegó a confirmarse, si tiene una.
+func releaseReservation(pcb *models.PCB) {
 	pcb.Mutex.Lock()
-	pcb.SwapRequested = false
+	token := pcb.Reservation
+	pcb.Reservation = ""
 	pcb.Mutex.Unlock()
-
-	slog.Debug(fmt.Sprintf("## (%d) - Pasa de SUSPENDED_READY a READY", pcb.PID))
-	TransitionProcessState(pcb, models.EstadoReady)
-	StartShortTermScheduler()
+	ReleaseUserMemory(pcb.PID, token)
 }
 
 func isProcessInSwap(pid uint) bool {
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/utils/list"
+)
+
+// Políticas para elegir qué procesos suspender cuando un proceso en NEW o SUSP_READY no entra en memoria.
+const (
+	PressurePolicyNone           = "NONE"            // Solo se suspende por suspension_time.
+	PressurePolicyLargest        = "LARGEST"         // Primero los procesos más grandes.
+	PressurePolicyLongestBlocked = "LONGEST_BLOCKED" // Primero los que llevan más tiempo en su estado.
+	PressurePolicyPriority       = "PRIORITY"        // Primero los de menor prioridad para SJF: la mayor ráfaga estimada.
+)
+
+var relievingPressure atomic.Bool // Hay una búsqueda de víctimas en curso.
+
+// pressureCandidate es un proceso que podría suspenderse, con los datos de la política tomados bajo su mutex.
+type pressureCandidate struct {
+	pcb      *models.PCB
+	size     int
+	waited   time.Duration
+	estimate float32
+}
+
+// relieveMemoryPressure suspende procesos en BLOCKED (y en READY, si está habilitado) hasta que el proceso
+// que no entró en memoria tenga lugar. Corre en segundo plano y a lo sumo una búsqueda a la vez.
+func relieveMemoryPressure(pid uint, size int) {
+	policy := models.KernelConfig.PressurePolicy
+	if policy == "" || policy == PressurePolicyNone {
+		return
+	}
+	if !relievingPressure.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer relievingPressure.Store(false)
+
+		maxVictims := models.KernelConfig.PressureMaxVictims
+		suspended := 0
+		for maxVictims <= 0 || suspended < maxVictims {
+			kernelSwapController.pmpMutex.Lock()
+			done, found := suspendNextVictim(pid, size, policy)
+			kernelSwapController.pmpMutex.Unlock()
+			if !found {
+				break
+			}
+			suspended++
+			// Memoria libera el espacio de la víctima recién cuando termina su SWAP.
+			<-done
+		}
+
+		if suspended > 0 {
+			slog.Debug("PMP: Procesos suspendidos por falta de memoria.", "PID esperando", pid, "suspendidos", suspended)
+		}
+	}()
+}
+
+// suspendNextVictim suspende la próxima víctima según la política, salvo que el proceso ya entre en memoria.
+// Devuelve el canal que se cierra al terminar su SWAP. Requiere pmpMutex tomado.
+func suspendNextVictim(pid uint, size int, policy string) (<-chan struct{}, bool) {
+	if CheckUserMemoryCapacity(pid, size) == nil {
+		return nil, false
+	}
+
+	victims := pressureCandidates(models.QueueBlocked, policy)
+	if models.KernelConfig.PressureReady {
+		victims = append(victims, readyPressureCandidates(pid, policy)...)
+	}
+	for _, victim := range victims {
+		if done, suspended := suspendForPressure(victim.pcb); suspended {
+			slog.Info(fmt.Sprintf("## (<%d>) - Suspendido por falta de memoria para el proceso <%d>", victim.pcb.PID, pid))
+			return done, true
+		}
+	}
+	return nil, false
+}
+
+// pressureCandidates devuelve los procesos de la cola que pueden suspenderse, ordenados según la política.
+func pressureCandidates(queue *list.ArrayList[*models.PCB], policy string) []pressureCandidate {
+	minWait := time.Duration(models.KernelConfig.PressureMinBlocked) * time.Millisecond
+	candidates := []pressureCandidate{}
+	for _, pcb := range queue.GetAll() {
+		pcb.Mutex.Lock()
+		candidate := pressureCandidate{
+			pcb:      pcb,
+			size:     pcb.Size,
+			waited:   time.Since(pcb.UltimoCambio),
+			estimate: pcb.RafagaEstimada,
+		}
+		eligible := !pinnedForIo(pcb) && !pcb.SwapRequested && candidate.waited >= minWait
+		pcb.Mutex.Unlock()
+		if eligible {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		switch policy {
+		case PressurePolicyLargest:
+			return candidates[i].size > candidates[j].size
+		case PressurePolicyPriority:
+			return candidates[i].estimate > candidates[j].estimate
+		default:
+			return candidates[i].waited > candidates[j].waited
+		}
+	})
+	return candidates
+}
+
+// readyPressureCandidates devuelve los procesos en READY que pueden suspenderse para el proceso que espera.
+// Una víctima tomada de READY pasa a SUSP_READY, que el PLP atiende antes que NEW: si el proceso que espera
+// no está en SUSP_READY delante de ella, el PMP la desuspendería enseguida y se la volvería a suspender.
+// Con PMCP se desuspende primero al más chico, así que solo sirven las víctimas más grandes que él.
+func readyPressureCandidates(pid uint, policy string) []pressureCandidate {
+	waiting, _, found := models.QueueSuspReady.Find(func(p *models.PCB) bool { return p.PID == pid })
+	if !found {
+		return nil
+	}
+	candidates := pressureCandidates(models.QueueReady, policy)
+	if models.KernelConfig.NewAlgorithm != "PMCP" {
+		return candidates
+	}
+
+	waiting.Mutex.Lock()
+	waitingSize := waiting.Size
+	waiting.Mutex.Unlock()
+	behind := []pressureCandidate{}
+	for _, candidate := range candidates {
+		if candidate.size > waitingSize {
+			behind = append(behind, candidate)
+		}
+	}
+	return behind
+}
+
+// suspendForPressure suspende el proceso y encola su paso a SWAP. Devuelve false si cambió de estado
+// mientras se elegía. Requiere pmpMutex tomado.
+func suspendForPressure(pcb *models.PCB) (<-chan struct{}, bool) {
+	pcb.Mutex.Lock()
+	state := pcb.EstadoActual
+	pcb.Mutex.Unlock()
+
+	switch state {
+	case models.EstadoBlocked:
+		return suspendBlockedForPressure(pcb)
+	case models.EstadoReady:
+		return suspendReadyForPressure(pcb)
+	default:
+		return nil, false
+	}
+}
+
+func suspendBlockedForPressure(pcb *models.PCB) (<-chan struct{}, bool) {
+	pcb.Mutex.Lock()
+	if pcb.EstadoActual != models.EstadoBlocked || pinnedForIo(pcb) || pcb.SwapRequested {
+		pcb.Mutex.Unlock()
+		return nil, false
+	}
+	if pcb.SuspensionTimer != nil {
+		pcb.SuspensionTimer.Stop()
+		pcb.SuspensionTimer = nil
+	}
+	suspendProcessLogic(pcb)
+	pcb.SwapRequested = true // El PMP no lo vuelve a tomar mientras se swapea acá.
+	pcb.Mutex.Unlock()
+
+	return requestSwapIn(pcb)
+}
+
+// suspendReadyForPressure saca al proceso de READY antes de suspenderlo para que el PCP no lo despache.
+func suspendReadyForPressure(pcb *models.PCB) (<-chan struct{}, bool) {
+	if _, _, taken := models.QueueReady.Take(func(p *models.PCB) bool { return p.PID == pcb.PID }); !taken {
+		return nil, false
+	}
+
+	TransitionProcessState(pcb, models.EstadoSuspendidoReady)
+	pcb.Mutex.Lock()
+	pcb.SwapRequested = true
+	pcb.Mutex.Unlock()
+	return requestSwapIn(pcb)
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strconv"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/cpu/models"
@@ -37,65 +39,102 @@ func ShortTermScheduler() {
 // dispatchAvailableProcesses busca una CPU libre y, si la hay, despacha un proceso.
 func dispatchAvailableProcesses() {
 	for kernelModels.QueueReady.Size() > 0 {
-		// 3. Busca una CPU libre ANTES de seleccionar un proceso.
-		cpu, found := kernelModels.ConnectedCpuMap.GetFirstFree()
-		if !found {
+		// 3. Verifica que haya CPUs libres ANTES de seleccionar un proceso.
+		freeCpus := kernelModels.ConnectedCpuMap.FreeKeys()
+		if len(freeCpus) == 0 {
 			slog.Debug("PCP: No hay CPUs libres en este momento. Esperando notificación.")
 			return // No hay CPUs, salimos y esperamos un nuevo aviso.
 		}
 
-		// 4. Si hay CPU, seleccionamos un proceso según el algoritmo.
-		pcb := selectProcessToExecute()
+		// 4. Seleccionamos un proceso según el algoritmo entre los que pueden usar alguna CPU libre.
+		// La afinidad se consulta antes de seleccionar: el predicado corre con la cola tomada y no puede
+		// tomar el mutex del PCB, que TransitionProcessState toma antes que el de la cola.
+		eligible := eligibleForFreeCpus(freeCpus)
+		pcb, index := selectProcessToExecute(func(p *kernelModels.PCB) bool {
+			return eligible[p.PID]
+		})
 		if pcb == nil {
-			slog.Warn("PCP: Se esperaba un proceso en READY pero no se obtuvo. Liberando CPU.")
-			kernelModels.ConnectedCpuMap.MarkAsFree(cpu.Id)
-			continue
+			slog.Debug("PCP: Ningún proceso en READY puede ejecutar en las CPUs libres. Esperando notificación.")
+			return
 		}
 
-		// 5. Despachamos el proceso a la CPU en una goroutine para no bloquear el planificador.
+		// 5. Se prefiere la CPU donde el proceso ejecutó por última vez (TLB y caché ya cargadas).
+		pcb.Mutex.Lock()
+		previousCpu, hardAffinity := pcb.PreviousCpu, pcb.HardAffinity
+		pcb.Mutex.Unlock()
+		cpu, found := kernelModels.ConnectedCpuMap.ReserveForProcess(previousCpu, hardAffinity)
+		if !found {
+			slog.Warn("PCP: No se pudo reservar una CPU para el proceso. Vuelve a READY en su lugar.", "PID", pcb.PID)
+			returnToReady(pcb, index)
+			return
+		}
+		recordAffinity(pcb, cpu)
+
+		// 6. Despachamos el proceso a la CPU en una goroutine para no bloquear el planificador.
 		go handleCpuExecution(pcb, cpu)
 	}
 }
 
+// returnToReady devuelve a READY, en la posición que ocupaba, un proceso que se seleccionó pero no se
+// pudo despachar. Así no pierde su lugar en el orden FIFO ni en el desempate de SJF.
+func returnToReady(pcb *kernelModels.PCB, index int) {
+	if err := kernelModels.QueueReady.Insert(min(index, kernelModels.QueueReady.Size()), pcb); err != nil {
+		kernelModels.QueueReady.Add(pcb)
+	}
+}
+
 // --- Lógica de Selección y Despacho ---
 
 // selectProcessToExecute contiene el SWITCH para los algoritmos de planificación.
-func selectProcessToExecute() *kernelModels.PCB {
+// Solo se consideran los procesos para los que eligible devuelve true. Devuelve también el índice que
+// el proceso ocupaba en READY.
+func selectProcessToExecute(eligible func(*kernelModels.PCB) bool) (*kernelModels.PCB, int) {
 	var pcb *kernelModels.PCB
+	var index int
 	var err error
 
 	switch kernelModels.KernelConfig.SchedulerAlgorithm {
 	case "FIFO":
-		pcb, err = scheduleFIFO()
+		pcb, index, err = scheduleFIFO(eligible)
 	case "SJF", "SRT":
-		pcb, err = scheduleShortestJobFirst()
+		pcb, index, err = scheduleShortestJobFirst(eligible)
 	default:
 		slog.Error("PCP: Algoritmo no reconocido.", "algoritmo", kernelModels.KernelConfig.SchedulerAlgorithm)
-		return nil
+		return nil, -1
 	}
 
 	if err != nil {
 		slog.Error("PCP: Error al obtener proceso de la cola READY.", "error", err)
-		return nil
+		return nil, -1
 	}
-	return pcb
+	return pcb, index
 }
 
-func scheduleFIFO() (*kernelModels.PCB, error) {
+func scheduleFIFO(eligible func(*kernelModels.PCB) bool) (*kernelModels.PCB, int, error) {
 	slog.Debug("PCP (FIFO): Seleccionando primer proceso de la cola READY.")
 	slog.Debug(fmt.Sprintf("CANTIDAD DE PROCESOS EN LA COLA READY %v", kernelModels.QueueReady.Size()))
-	return kernelModels.QueueReady.Dequeue()
+	pcb, index, found := kernelModels.QueueReady.Take(eligible)
+	if !found {
+		return nil, -1, nil
+	}
+	return pcb, index, nil
 }
 
-func scheduleShortestJobFirst() (*kernelModels.PCB, error) {
+func scheduleShortestJobFirst(eligible func(*kernelModels.PCB) bool) (*kernelModels.PCB, int, error) {
 	slog.Debug("PCP (SJF/SRT): Buscando proceso con la ráfaga más corta en READY.")
 	if kernelModels.QueueReady.Size() == 0 {
-		return nil, fmt.Errorf("la cola READY está vacía")
+		return nil, -1, fmt.Errorf("la cola READY está vacía")
 	}
 
 	allReadyProcesses := kernelModels.QueueReady.GetAll()
 	if len(allReadyProcesses) == 0 {
-		return nil, fmt.Errorf("error al obtener procesos de la cola READY")
+		return nil, -1, fmt.Errorf("error al obtener procesos de la cola READY")
+	}
+	allReadyProcesses = slices.DeleteFunc(allReadyProcesses, func(p *kernelModels.PCB) bool {
+		return !eligible(p)
+	})
+	if len(allReadyProcesses) == 0 {
+		return nil, -1, nil
 	}
 
 	// --- LÓGICA ANTI-INANICIÓN (LA TUYA, QUE ES LA CORRECTA) ---
@@ -134,29 +173,84 @@ func scheduleShortestJobFirst() (*kernelModels.PCB, error) {
 
 	// --- MEJORA DE SEGURIDAD ---
 	// Se reemplaza la eliminación por índice por una eliminación segura por PID.
-	kernelModels.QueueReady.RemoveWhere(func(p *kernelModels.PCB) bool {
+	// Si otro planificador lo sacó de READY mientras se elegía (p. ej. para suspenderlo), no se despacha.
+	_, index, taken := kernelModels.QueueReady.Take(func(p *kernelModels.PCB) bool {
 		return p.PID == pcbToExecute.PID
 	})
+	if !taken {
+		return nil, -1, nil
+	}
 	// -------------------------
 
-	return pcbToExecute, nil
+	return pcbToExecute, index, nil
 }
 
 func handleCpuExecution(pcb *kernelModels.PCB, cpu *models.CpuN) {
 	cpu.PIDExecuting = pcb.PID
-	kernelModels.ConnectedCpuMap.Set(strconv.Itoa(cpu.Id), cpu)
+	kernelModels.ConnectedCpuMap.Set(kernelModels.CpuKey(cpu), cpu)
 
 	pcb.BurstStartTime = time.Now()
 
 	TransitionProcessState(pcb, kernelModels.EstadoExecuting)
-	result := sendProcessToExecute(pcb, cpu)
+	ensureCpuCoherence(pcb, cpu)
+
+	dispatchId := registerDispatch(pcb, cpu)
+	err := sendProcessToExecute(pcb, cpu, dispatchId)
+	if err == nil {
+		// El resultado de la ráfaga llega de forma asíncrona a /kernel/resultado-rafaga.
+		return
+	}
+
+	// Si el despacho ya no está pendiente, el resultado llegó (o el proceso se recuperó) por otro camino.
+	if _, pending := takeDispatch(dispatchId); !pending {
+		return
+	}
+	if !kernelModels.ConnectedCpuMap.ReleaseProcess(cpu, pcb.PID) {
+		slog.Warn("PCP: El proceso ya fue liberado de la CPU.", "PID", pcb.PID, "cpu_id", cpu.Id, "core", cpu.Core)
+		return
+	}
+
+	// Si la CPU no responde se la da de baja y el proceso vuelve a READY desde su último PC.
+	if errors.Is(err, errCpuUnreachable) {
+		DeregisterCpu(cpu.Ip, cpu.Port, "no respondió al despacho de un proceso")
+		recoverProcessFromCpu(pcb, cpu)
+		return
+	}
+
+	slog.Warn("PCP: La CPU rechazó el proceso. Vuelve a READY.", "PID", pcb.PID, "cpu_id", cpu.Id, "core", cpu.Core, "error", err)
+	TransitionProcessState(pcb, kernelModels.EstadoReady)
+
+	// Si el núcleo sigue ejecutando otra cosa, liberarlo ya haría que se le vuelva a despachar enseguida.
+	if errors.Is(err, errCoreBusy) {
+		go waitForCoreToFree(cpu)
+	} else {
+		kernelModels.ConnectedCpuMap.MarkAsFree(cpu)
+	}
+	StartShortTermScheduler()
+}
+
+// HandleBurstCompletion recibe el resultado de una ráfaga informado por una CPU. Se rechaza si el despacho
+// no está pendiente (ya se procesó, o la CPU fue dada de baja y el proceso se recuperó).
+func HandleBurstCompletion(result kernelModels.PCBExecuteRequest) error {
+	dispatch, found := takeDispatchForPid(result.DispatchId, result.PID)
+	if !found {
+		return ErrStaleDispatch
+	}
+	if !kernelModels.ConnectedCpuMap.ReleaseProcess(dispatch.cpu, dispatch.pcb.PID) {
+		return ErrStaleDispatch
+	}
+
+	go processBurstResult(dispatch.pcb, dispatch.cpu, result)
+	return nil
+}
 
+// processBurstResult actualiza el PCB con el resultado de la ráfaga y lo deriva al estado que corresponda.
+func processBurstResult(pcb *kernelModels.PCB, cpu *models.CpuN, result kernelModels.PCBExecuteRequest) {
 	// La CPU se marca como libre inmediatamente después de recibir la respuesta,
 	// permitiendo que el planificador la asigne a otro proceso mientras
 	// el Kernel gestiona el resultado del proceso actual.
-	cpu.PIDExecuting = 0
-	kernelModels.ConnectedCpuMap.MarkAsFree(cpu.Id)
-	slog.Debug("PCP: CPU liberada.", "cpu_id", cpu.Id)
+	kernelModels.ConnectedCpuMap.MarkAsFree(cpu)
+	slog.Debug("PCP: CPU liberada.", "cpu_id", cpu.Id, "core", cpu.Core)
 	// --------------------------
 
 	// La lógica para actualizar la ráfaga estimada ahora depende de si el proceso fue interrumpido o no.
@@ -188,11 +282,18 @@ func handleCpuExecution(pcb *kernelModels.PCB, cpu *models.CpuN) {
 		TransitionProcessState(pcb, kernelModels.EstadoExit)
 		StartLongTermScheduler()
 
-	case kernelModels.NeedReplan, kernelModels.NeedInterrupt:
-		slog.Debug("PCP: CPU devolvió el proceso para replanificación/interrupción.", "PID", pcb.PID)
+	case kernelModels.NeedReplan:
+		slog.Debug("PCP: CPU devolvió el proceso para replanificación.", "PID", pcb.PID)
 		TransitionProcessState(pcb, kernelModels.EstadoReady)
 		StartShortTermScheduler()
 
+	case kernelModels.NeedInterrupt:
+		// La CPU puede haberse quedado con páginas modificadas y traducciones del proceso.
+		pcb.Mutex.Lock()
+		pcb.LastCpu = cpu
+		pcb.Mutex.Unlock()
+		handleInterruptedProcess(pcb, result.InterruptType)
+
 	case kernelModels.NeedExecuteSyscall:
 		slog.Debug("PCP: CPU devolvió el proceso por syscall. Derivando...", "PID", pcb.PID)
 		handleBlockingSyscall(result, pcb)
@@ -206,32 +307,36 @@ func handleCpuExecution(pcb *kernelModels.PCB, cpu *models.CpuN) {
 	StartShortTermScheduler()
 }
 
-func sendProcessToExecute(pcb *kernelModels.PCB, cpu *models.CpuN) kernelModels.PCBExecuteRequest {
+// sendProcessToExecute envía el proceso a la CPU. La CPU responde apenas lo acepta.
+// Devuelve errCpuUnreachable si la CPU no pudo ser contactada.
+func sendProcessToExecute(pcb *kernelModels.PCB, cpu *models.CpuN, dispatchId uint64) error {
 	slog.Info(fmt.Sprintf("## (%d) - Enviando a ejecutar a CPU %d", pcb.PID, cpu.Id))
 
 	request := kernelModels.PCBExecuteRequest{
-		PID: pcb.PID,
-		PC:  pcb.PC,
+		PID:        pcb.PID,
+		PC:         pcb.PC,
+		DispatchId: dispatchId,
 	}
 
 	body, err := json.Marshal(request)
 	if err != nil {
 		slog.Error("PCP: Error al serializar PCB para enviar a CPU.", "PID", pcb.PID, "error", err)
-		return kernelModels.PCBExecuteRequest{StatusCodePCB: kernelModels.NeedReplan, PC: pcb.PC}
+		return err
 	}
 
-	resp, err := client.DoRequest(cpu.Port, cpu.Ip, "POST", "cpu/exec", body)
+	query := fmt.Sprintf("cpu/exec?core=%d", cpu.Core)
+	resp, err := client.DoRequest(cpu.Port, cpu.Ip, "POST", query, body)
 	if err != nil {
-		slog.Error("PCP: Error de comunicación con la CPU.", "cpu_id", cpu.Id, "error", err)
-		return kernelModels.PCBExecuteRequest{StatusCodePCB: kernelModels.NeedReplan, PC: pcb.PC}
-	}
-	defer resp.Body.Close()
-
-	var pcbResult kernelModels.PCBExecuteRequest
-	if err := json.NewDecoder(resp.Body).Decode(&pcbResult); err != nil {
-		slog.Error("PCP: Error al decodificar respuesta de la CPU.", "cpu_id", cpu.Id, "error", err)
-		return kernelModels.PCBExecuteRequest{StatusCodePCB: kernelModels.NeedReplan, PC: pcb.PC}
+		if resp == nil {
+			slog.Error("PCP: Error de comunicación con la CPU.", "cpu_id", cpu.Id, "error", err)
+			return fmt.Errorf("%w: %v", errCpuUnreachable, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("%w: %v", errCoreBusy, err)
+		}
+		return err
 	}
-
-	return pcbResult
+	resp.Body.Close()
+	return nil
 }
@@ -11,8 +11,6 @@ import (
 // suspendProcessLogic contiene la lógica para mover un proceso a SUSPEND_BLOCKED.
 // Se asume que el Mutex del PCB ya fue adquirido antes de llamar a esta función.
 func suspendProcessLogic(pcb *models.PCB) {
-	slog.Debug(fmt.Sprintf("## (%d) - Proceso supera tiempo máximo en BLOCKED. Pasa a SUSPEND_BLOCKED.", pcb.PID))
-
 	// 1. Calculamos el tiempo que estuvo en BLOCKED y actualizamos la métrica.
 	oldState := pcb.EstadoActual
 	if !pcb.UltimoCambio.IsZero() {
@@ -44,7 +42,9 @@ func StartSuspensionTimer(pcb *models.PCB) {
 		defer pcb.Mutex.Unlock()
 
 		// Verificamos si el proceso AÚN está en BLOCKED cuando el timer se dispara.
-		if pcb.EstadoActual == models.EstadoBlocked {
+		// Con una operación de I/O con datos pendiente sus páginas tienen que seguir en memoria.
+		if pcb.EstadoActual == models.EstadoBlocked && !pinnedForIo(pcb) {
+			slog.Debug(fmt.Sprintf("## (%d) - Proceso supera tiempo máximo en BLOCKED. Pasa a SUSPEND_BLOCKED.", pcb.PID))
 			suspendProcessLogic(pcb)
 		}
 		// Si ya no está en BLOCKED, no hacemos nada, el timer ya fue detenido.
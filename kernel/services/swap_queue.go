@@ -0,0 +1,226 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
+)
+
+// Operaciones de SWAP que el Kernel le pide a Memoria.
+const (
+	swapOperationIn  = "swap_in"  // De memoria principal a SWAP (memoria/putSwap).
+	swapOperationOut = "swap_out" // De SWAP a memoria principal (memoria/removeSwap).
+)
+
+// swapJob es una operación de SWAP pendiente o en curso. Un proceso tiene a lo sumo una.
+type swapJob struct {
+	pcb        *models.PCB
+	operation  string
+	onDone     func(error) // Continúa la decisión del PMP cuando Memoria responde. No se llama si se canceló.
+	enqueuedAt time.Time
+	startedAt  time.Time // Cero mientras espera un worker.
+	cancelled  bool
+	done       chan struct{}
+}
+
+// SwapStats resume la actividad de la cola de SWAP para el endpoint y el resumen de cierre.
+type SwapStats struct {
+	Workers          int     `json:"workers"`
+	QueueDepth       int     `json:"queue_depth"`
+	MaxQueueDepth    int     `json:"max_queue_depth"`
+	Running          int     `json:"running"`
+	Completed        int     `json:"completed"`
+	Failed           int     `json:"failed"`
+	Cancelled        int     `json:"cancelled"`
+	AverageWaitMs    float64 `json:"average_wait_ms"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+	MaxLatencyMs     int64   `json:"max_latency_ms"`
+}
+
+type KernelSwapController struct {
+	mutex    sync.Mutex
+	notEmpty *sync.Cond
+	jobs     map[uint]*swapJob // PID -> operación pendiente o en curso
+	pending  []*swapJob
+	pmpMutex sync.Mutex // Serializa las decisiones del PMP. Las llamadas a Memoria las hacen los workers sin tomarlo.
+
+	workers      int
+	running      int
+	maxDepth     int
+	completed    int
+	failed       int
+	cancelled    int
+	measured     int // Operaciones que llegaron a enviarse a Memoria, base de los promedios.
+	totalWait    time.Duration
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+var kernelSwapController = newKernelSwapController()
+
+func newKernelSwapController() *KernelSwapController {
+	controller := &KernelSwapController{jobs: make(map[uint]*swapJob)}
+	controller.notEmpty = sync.NewCond(&controller.mutex)
+	return controller
+}
+
+// StartSwapWorkers lanza los workers que atienden la cola de SWAP. Como mucho esa cantidad de operaciones
+// se le piden a Memoria a la vez.
+func StartSwapWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	kernelSwapController.mutex.Lock()
+	kernelSwapController.workers = workers
+	kernelSwapController.mutex.Unlock()
+
+	for worker := 0; worker < workers; worker++ {
+		go swapWorker()
+	}
+	slog.Debug("PMP: Workers de SWAP iniciados", "workers", workers)
+}
+
+// enqueueSwap encola la operación del proceso. Devuelve false si ya tenía una pendiente o en curso.
+// El canal devuelto se cierra cuando la operación termina o se cancela.
+func enqueueSwap(pcb *models.PCB, operation string, onDone func(error)) (<-chan struct{}, bool) {
+	controller := kernelSwapController
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	if current, exists := controller.jobs[pcb.PID]; exists {
+		slog.Debug("Operación ya activa para proceso", "PID", pcb.PID, "current_operation", current.operation, "requested_operation", operation)
+		return nil, false
+	}
+	job := &swapJob{pcb: pcb, operation: operation, onDone: onDone, enqueuedAt: time.Now(), done: make(chan struct{})}
+	controller.jobs[pcb.PID] = job
+	controller.pending = append(controller.pending, job)
+	controller.maxDepth = max(controller.maxDepth, len(controller.pending))
+	controller.notEmpty.Signal()
+	slog.Debug("Operación de kernel registrada", "PID", pcb.PID, "operation", operation)
+	return job.done, true
+}
+
+// hasSwapJob indica si el proceso tiene una operación de SWAP pendiente o en curso.
+func hasSwapJob(pid uint) bool {
+	kernelSwapController.mutex.Lock()
+	defer kernelSwapController.mutex.Unlock()
+	_, exists := kernelSwapController.jobs[pid]
+	return exists
+}
+
+// CancelSwap descarta la operación de SWAP de un proceso que finaliza. Si todavía esperaba un worker no se
+// envía a Memoria; si ya estaba en curso se espera a que Memoria responda, para no liberar el proceso a mitad
+// del SWAP, y se descarta su continuación.
+func CancelSwap(pid uint) {
+	controller := kernelSwapController
+	controller.mutex.Lock()
+	job, exists := controller.jobs[pid]
+	if !exists {
+		controller.mutex.Unlock()
+		return
+	}
+	job.cancelled = true
+	if job.startedAt.IsZero() {
+		controller.pending = slices.DeleteFunc(controller.pending, func(pending *swapJob) bool { return pending == job })
+		delete(controller.jobs, pid)
+		controller.cancelled++
+		close(job.done)
+		controller.mutex.Unlock()
+		slog.Debug("PMP: Operación de SWAP cancelada antes de enviarse a Memoria.", "PID", pid, "operation", job.operation)
+		return
+	}
+	controller.mutex.Unlock()
+
+	slog.Debug("PMP: Esperando que Memoria termine el SWAP de un proceso que finaliza.", "PID", pid, "operation", job.operation)
+	<-job.done
+}
+
+func swapWorker() {
+	controller := kernelSwapController
+	for {
+		controller.mutex.Lock()
+		for len(controller.pending) == 0 {
+			controller.notEmpty.Wait()
+		}
+		job := controller.pending[0]
+		controller.pending = controller.pending[1:]
+		job.startedAt = time.Now()
+		controller.running++
+		controller.mutex.Unlock()
+
+		var err error
+		if job.operation == swapOperationIn {
+			err = putSwap(job.pcb)
+		} else {
+			err = removeSwap(job.pcb)
+		}
+		finishSwapJob(job, err)
+	}
+}
+
+// finishSwapJob registra las métricas de la operación y, si no se canceló, continúa la decisión del PMP.
+func finishSwapJob(job *swapJob, err error) {
+	controller := kernelSwapController
+	latency := time.Since(job.startedAt)
+
+	controller.mutex.Lock()
+	delete(controller.jobs, job.pcb.PID)
+	controller.running--
+	controller.measured++
+	controller.totalWait += job.startedAt.Sub(job.enqueuedAt)
+	controller.totalLatency += latency
+	controller.maxLatency = max(controller.maxLatency, latency)
+	switch {
+	case job.cancelled:
+		controller.cancelled++
+	case err != nil:
+		controller.failed++
+	default:
+		controller.completed++
+	}
+	cancelled := job.cancelled
+	controller.mutex.Unlock()
+	close(job.done)
+
+	slog.Debug("Operación de kernel completada", "PID", job.pcb.PID, "operation", job.operation, "latencia_ms", latency.Milliseconds())
+	if cancelled {
+		return
+	}
+	if job.onDone != nil {
+		job.onDone(err)
+	}
+}
+
+// GetSwapStats devuelve el estado de la cola de SWAP y la latencia de las operaciones terminadas.
+func GetSwapStats() SwapStats {
+	controller := kernelSwapController
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+
+	stats := SwapStats{
+		Workers:       controller.workers,
+		QueueDepth:    len(controller.pending),
+		MaxQueueDepth: controller.maxDepth,
+		Running:       controller.running,
+		Completed:     controller.completed,
+		Failed:        controller.failed,
+		Cancelled:     controller.cancelled,
+		MaxLatencyMs:  controller.maxLatency.Milliseconds(),
+	}
+	if controller.measured > 0 {
+		stats.AverageWaitMs = float64(controller.totalWait.Milliseconds()) / float64(controller.measured)
+		stats.AverageLatencyMs = float64(controller.totalLatency.Milliseconds()) / float64(controller.measured)
+	}
+	return stats
+}
+
+// LogSwapStats registra en el log un resumen de la actividad de SWAP.
+func LogSwapStats() {
+	stats := GetSwapStats()
+	slog.Info(fmt.Sprintf("## SWAP - Completadas: %d - Fallidas: %d - Canceladas: %d - Espera promedio: %.2f ms - Latencia promedio: %.2f ms - Latencia máxima: %d ms - Cola máxima: %d",
+		stats.Completed, stats.Failed, stats.Cancelled, stats.AverageWaitMs, stats.AverageLatencyMs, stats.MaxLatencyMs, stats.MaxQueueDepth))
+}
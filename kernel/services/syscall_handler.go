@@ -16,7 +16,7 @@ func handleBlockingSyscall(result kernelModels.PCBExecuteRequest, pcb *kernelMod
 	case "DUMP_MEMORY":
 		executeDumpMemorySyscall(pcb)
 
-	case "IO":
+	case "IO", kernelModels.SyscallIoRead, kernelModels.SyscallIoWrite, kernelModels.SyscallDmaRead, kernelModels.SyscallDmaWrite:
 		executeIOSyscall(pcb, result.SyscallRequest)
 
 	default:
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"strconv"
+	"net/http"
 
 	ioModel "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/io/models"
 	kernelModels "github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/kernel/models"
@@ -14,14 +14,22 @@ import (
 // executeIOSyscall maneja la petición de I/O de un proceso.
 func executeIOSyscall(pcb *kernelModels.PCB, request kernelModels.SyscallRequest) {
 	deviceName := request.Values[0]
-	time, err := strconv.Atoi(request.Values[1])
-	if err != nil {
+	if _, err := kernelModels.IoRequestTime(&request); err != nil {
 		slog.Error("Syscall IO con tiempo inválido. Finalizando proceso.", "PID", pcb.PID)
 		TransitionProcessState(pcb, kernelModels.EstadoExit)
 		StartLongTermScheduler()
 		return
 	}
+	if kernelModels.IsDataIo(request.Type) && !kernelModels.IsDmaIo(request.Type) && len(request.Transfers) == 0 {
+		slog.Error("Syscall de I/O con datos sin memoria asociada. Finalizando proceso.", "tipo", request.Type, "PID", pcb.PID)
+		TransitionProcessState(pcb, kernelModels.EstadoExit)
+		StartLongTermScheduler()
+		return
+	}
 
+	if kernelModels.IsDataIo(request.Type) {
+		pinForIo(pcb, true)
+	}
 	TransitionProcessState(pcb, kernelModels.EstadoBlocked)
 	slog.Info(fmt.Sprintf("## (<%d>) - Bloqueado por IO: <%s>", pcb.PID, deviceName))
 
@@ -41,68 +49,137 @@ func executeIOSyscall(pcb *kernelModels.PCB, request kernelModels.SyscallRequest
 		kernelModels.WaitingForDeviceManager.Enqueue(deviceName, pcb)
 	} else {
 		slog.Debug("Dispositivo libre encontrado. Enviando proceso a I/O.", "dispositivo", deviceName, "PID", pcb.PID)
-		dispatchToDevice(pcb, device, time)
+		kernelModels.WaitingForDeviceManager.MoveHead(deviceName, &request)
+		dispatchToDevice(pcb, device, &request)
 	}
 }
 
 // dispatchToDevice envía la solicitud de I/O al módulo correspondiente.
-func dispatchToDevice(pcb *kernelModels.PCB, device *ioModel.Device, time int) {
-	device.PID = pcb.PID
+func dispatchToDevice(pcb *kernelModels.PCB, device *ioModel.Device, syscall *kernelModels.SyscallRequest) {
+	kernelModels.ConnectedDeviceManager.Assign(device, pcb.PID)
+	trackIo(pcb, device, syscall)
+
+	time, _ := kernelModels.IoRequestTime(syscall)
+	request := kernelModels.DeviceRequest{
+		Pid:            pcb.PID,
+		SuspensionTime: time,
+		Operation:      syscall.Type,
+	}
+	if kernelModels.IsDataIo(syscall.Type) {
+		request.Offset = kernelModels.IoRequestOffset(syscall)
+		request.Transfers = syscall.Transfers
+	}
 
 	go func() {
-		request := struct {
-			Pid            uint `json:"pid"`
-			SuspensionTime int  `json:"suspensionTime"`
-		}{
-			Pid:            pcb.PID,
-			SuspensionTime: time,
+		if kernelModels.IsDmaIo(syscall.Type) {
+			if err := prepareDma(pcb.PID, syscall, &request); err != nil {
+				slog.Error("No se pudo preparar la transferencia DMA. Finalizando proceso.", "PID", pcb.PID, "error", err)
+				if _, pending := takeIo(pcb.PID); !pending {
+					return
+				}
+				kernelModels.ConnectedDeviceManager.Release(device.Ip, device.Port, pcb.PID)
+				pinForIo(pcb, false)
+				TransitionProcessState(pcb, kernelModels.EstadoExit)
+				StartLongTermScheduler()
+				return
+			}
 		}
 
 		body, err := json.Marshal(request)
 		if err != nil {
 			slog.Error("Error al serializar petición de I/O. Finalizando proceso.", "PID", pcb.PID)
+			takeIo(pcb.PID)
+			kernelModels.ConnectedDeviceManager.Release(device.Ip, device.Port, pcb.PID)
+			pinForIo(pcb, false)
 			TransitionProcessState(pcb, kernelModels.EstadoExit)
 			StartLongTermScheduler()
 			return
 		}
 
-		_, err = client.DoRequest(device.Port, device.Ip, "POST", "io", body)
+		resp, err := client.DoRequest(device.Port, device.Ip, "POST", "io", body)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if resp != nil && resp.StatusCode == http.StatusServiceUnavailable {
+			// La instancia empezó a cerrarse antes de recibir la solicitud.
+			HandleInterruptedIo(pcb.PID)
+			return
+		}
 		if err != nil {
 			slog.Error("Error de comunicación con el módulo de I/O. Finalizando proceso.", "dispositivo", device.Name, "PID", pcb.PID)
-			kernelModels.ConnectedDeviceManager.MarkAsFreeByPort(device.Port)
+			if _, pending := takeIo(pcb.PID); !pending {
+				return // El plazo ya venció y se resolvió por otro camino.
+			}
+			kernelModels.ConnectedDeviceManager.Release(device.Ip, device.Port, pcb.PID)
 			TransitionProcessState(pcb, kernelModels.EstadoExit)
 			StartLongTermScheduler()
 		}
 	}()
 }
 
-// TryToDispatchNextIO revisa la cola de espera de un dispositivo y, si hay procesos, despacha el siguiente.
+// TryToDispatchNextIO revisa la cola de espera de un dispositivo y despacha procesos mientras haya slots libres.
 func TryToDispatchNextIO(deviceName string) {
 	slog.Debug("Intentando despachar el próximo proceso para I/O.", "dispositivo", deviceName)
 
-	device, exists := kernelModels.ConnectedDeviceManager.GetFreeByName(deviceName)
-	if !exists || device == nil {
-		return
-	}
+	for {
+		device, exists := kernelModels.ConnectedDeviceManager.GetFreeByName(deviceName)
+		if !exists || device == nil {
+			return
+		}
 
-	pcb, found := kernelModels.WaitingForDeviceManager.Dequeue(deviceName)
-	if !found {
-		kernelModels.ConnectedDeviceManager.MarkAsFreeByPort(device.Port)
-		return
-	}
+		pcb, found := kernelModels.WaitingForDeviceManager.Dequeue(deviceName)
+		if !found {
+			kernelModels.ConnectedDeviceManager.CancelReservation(device)
+			return
+		}
 
-	slog.Debug("Despachando proceso en espera a I/O.", "PID", pcb.PID, "dispositivo", deviceName)
+		slog.Debug("Despachando proceso en espera a I/O.", "PID", pcb.PID, "dispositivo", deviceName)
 
-	// Recuperamos el tiempo correcto desde el PCB.
-	if pcb.PendingIoRequest == nil {
-		slog.Error("El PCB en espera no tenía una solicitud de I/O pendiente. Finalizando.", "PID", pcb.PID)
-		TransitionProcessState(pcb, kernelModels.EstadoExit)
-		StartLongTermScheduler()
-		return
+		// Recuperamos el tiempo correcto desde el PCB.
+		if pcb.PendingIoRequest == nil {
+			slog.Error("El PCB en espera no tenía una solicitud de I/O pendiente. Finalizando.", "PID", pcb.PID)
+			kernelModels.ConnectedDeviceManager.CancelReservation(device)
+			TransitionProcessState(pcb, kernelModels.EstadoExit)
+			StartLongTermScheduler()
+			continue
+		}
+
+		request := pcb.PendingIoRequest
+		pcb.PendingIoRequest = nil // Limpiamos la solicitud pendiente.
+
+		dispatchToDevice(pcb, device, request)
 	}
+}
 
-	time, _ := strconv.Atoi(pcb.PendingIoRequest.Values[1])
-	pcb.PendingIoRequest = nil // Limpiamos la solicitud pendiente.
+// GetDeviceInstances devuelve las instancias conectadas con su utilización y la cola de espera de su dispositivo.
+func GetDeviceInstances() []kernelModels.DeviceInstanceStatus {
+	instances := kernelModels.ConnectedDeviceManager.Instances()
+	for i := range instances {
+		instances[i].QueueLength = kernelModels.WaitingForDeviceManager.Length(instances[i].Name)
+	}
+	return instances
+}
 
-	dispatchToDevice(pcb, device, time)
+// GetDeviceStats devuelve las métricas acumuladas de cada dispositivo junto con la longitud actual de su cola.
+func GetDeviceStats() []kernelModels.DeviceStats {
+	stats := kernelModels.DeviceAccounting.Snapshot()
+	for i := range stats {
+		stats[i].QueueLength = kernelModels.WaitingForDeviceManager.Length(stats[i].Name)
+	}
+	return stats
+}
+
+// LogDeviceStats registra en el log un resumen de la actividad de I/O de cada dispositivo.
+func LogDeviceStats() {
+	for _, stats := range GetDeviceStats() {
+		slog.Info(fmt.Sprintf("## Dispositivo <%s> - Atendidas: %d - Tiempo ocupado: %d ms - Espera promedio: %.2f ms - Cola máxima: %d",
+			stats.Name, stats.Served, stats.BusyTimeMs, stats.AverageWaitMs, stats.MaxQueueLength))
+		for _, instance := range stats.Instances {
+			slog.Info(fmt.Sprintf("##   Instancia %s:%d - Atendidas: %d - Tiempo ocupado: %d ms",
+				instance.Ip, instance.Port, instance.Served, instance.BusyTimeMs))
+		}
+		for pid, elapsed := range stats.PidTimeMs {
+			slog.Debug(fmt.Sprintf("##   (<%d>) - Tiempo en I/O: %d ms", pid, elapsed))
+		}
+	}
 }
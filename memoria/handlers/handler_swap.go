@@ -97,7 +97,7 @@ func RemoveProcessInSwapHandler(w http.ResponseWriter, r *http.Request) {
 	defer setProcessBeingProcessed(req.PID, false)
 
 	// Ejecutar operación de forma sincronizada
-	if err := services.RemoveProcessInSwap(req.PID); err != nil {
+	if err := services.RemoveProcessInSwap(req.PID, req.Token); err != nil {
 		slog.Error("Error en REMOVE PROCESS IN SWAP", "PID", req.PID, "error", err)
 		http.Error(w, "Internal Server Error: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"math"
 	"net/http"
@@ -58,3 +59,48 @@ func UserMemoryCapacityHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Debug("Capacidad de memoria verificada exitosamente", "PID", pid, "framesLibres", freeFramesCount, "framesNecesarios", pageCount)
 	w.WriteHeader(http.StatusOK)
 }
+
+// ReserveFramesHandler aparta los frames que necesita un proceso para cargarlo o sacarlo de SWAP.
+// Responde 204, igual que UserMemoryCapacityHandler, si no hay frames suficientes.
+func ReserveFramesHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	token, frames, err := services.ReserveFrames(req.PID, req.Size)
+	if errors.Is(err, services.ErrNotEnoughFrames) {
+		slog.Debug("Memoria insuficiente para reservar", "PID", req.PID, "Size", req.Size)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := models.ReservationResponse{
+		Token:  token,
+		Frames: frames,
+		TTLMs:  int(services.ReservationTTL().Milliseconds()),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReleaseReservationHandler devuelve los frames de una reserva que no se va a confirmar.
+func ReleaseReservationHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ReleaseReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !services.ReleaseReservation(req.Token) {
+		slog.Debug("Reserva inexistente o ya confirmada", "token", req.Token)
+	}
+	w.WriteHeader(http.StatusOK)
+}
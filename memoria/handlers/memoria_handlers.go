@@ -67,6 +67,48 @@ func GetInstructionHandler(configPath string) func(http.ResponseWriter, *http.Re
 	}
 }
 
+// GetInstructionWindowHandler devuelve una ventana de instrucciones en un único pedido.
+// Se aplica un solo retardo de memoria por ventana.
+func GetInstructionWindowHandler(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(time.Duration(models.MemoryConfig.MemoryDelay) * time.Millisecond)
+
+	queryParams := r.URL.Query()
+	pid, errPid := strconv.ParseUint(queryParams.Get("pid"), 10, 64)
+	pc, errPc := strconv.Atoi(queryParams.Get("pc"))
+	count, errCount := strconv.Atoi(queryParams.Get("cantidad"))
+	if errPid != nil || errPc != nil || errCount != nil {
+		http.Error(w, "Parámetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	instructions, total, err := services.GetInstructionWindow(uint(pid), pc, count)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		slog.Error(fmt.Sprintf("error: %s", err.Error()))
+		return
+	}
+
+	slog.Info(fmt.Sprintf("## PID: <%d> - Obtener instrucciones: <%d> a <%d>", pid, pc, pc+len(instructions)-1))
+	server.SendJsonResponse(w, models.InstructionsWindowResponse{
+		PC:           pc,
+		Instructions: instructions,
+		Total:        total,
+	})
+}
+
+// InstructionFetchesHandler registra en las métricas las instrucciones que la CPU consumió de su buffer.
+func InstructionFetchesHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.InstructionFetchesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	services.AddInstructionFetches(request.Pid, request.Count)
+	slog.Debug("Fetches informados por CPU", "pid", request.Pid, "cantidad", request.Count)
+	w.WriteHeader(http.StatusOK)
+}
+
 func ReserveMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
@@ -82,7 +124,7 @@ func ReserveMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	//slog.Info("Ruta recibida para cargar instrucciones", "path", request.Path)
 	time.Sleep(time.Duration(models.MemoryConfig.MemoryDelay) * time.Millisecond)
 
-	err := services.ReserveMemory(request.PID, request.Size, request.Path)
+	err := services.ReserveMemory(request.PID, request.Size, request.Path, request.Token)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error al reservar memoria: %v", err), http.StatusInternalServerError)
 		return
@@ -44,6 +44,8 @@ func main() {
 
 	//Manejo de memoria del sistema
 	http.HandleFunc("GET /memoria/instruccion", memoryHandler.GetInstructionHandler(models.MemoryConfig.ScriptsPath))
+	http.HandleFunc("GET /memoria/instrucciones", memoryHandler.GetInstructionWindowHandler)
+	http.HandleFunc("POST /memoria/instrucciones/consumidas", memoryHandler.InstructionFetchesHandler)
 
 	//Acceso a tabla de paginas
 	http.HandleFunc("POST /memoria/buscarFrame", memoryHandler.SearchFrameHandler)
@@ -75,6 +77,10 @@ func main() {
 	//Consultar si hay espacio suficiente para un proceso
 	http.HandleFunc("POST /memoria/capacidadUserMemory", memoryHandler.UserMemoryCapacityHandler)
 
+	//Reservar frames antes de cargar un proceso o sacarlo de swap
+	http.HandleFunc("POST /memoria/reservarFrames", memoryHandler.ReserveFramesHandler)
+	http.HandleFunc("POST /memoria/liberarReserva", memoryHandler.ReleaseReservationHandler)
+
 	slog.Debug("Memoria lista")
 
 	err = server.InitServer(models.MemoryConfig.PortMemory)
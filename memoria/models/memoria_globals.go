@@ -27,6 +27,7 @@ type Config struct {
 	LogLevel       string `json:"log_level"`
 	DumpPath       string `json:"dump_path"`
 	ScriptsPath    string `json:"scripts_path"`
+	ReservationTTL int    `json:"reservation_ttl"` // Milisegundos que una reserva de frames espera su confirmación
 }
 
 type InstructionsResponse struct {
@@ -38,10 +39,25 @@ type InstructionResponse struct {
 	IsLast      bool
 }
 
+// InstructionsWindowResponse es una ventana de instrucciones a partir de PC.
+// Total es la cantidad de instrucciones del proceso, para que la CPU sepa cuál es la última.
+type InstructionsWindowResponse struct {
+	PC           int
+	Instructions []string
+	Total        int
+}
+
+// InstructionFetchesRequest informa cuántas instrucciones consumió la CPU desde su buffer.
+type InstructionFetchesRequest struct {
+	Pid   uint `json:"pid"`
+	Count int  `json:"count"`
+}
+
 type MemoryRequest struct {
-	PID  uint   `json:"pid"`
-	Size int    `json:"size"`
-	Path string `json:"path"`
+	PID   uint   `json:"pid"`
+	Size  int    `json:"size"`
+	Path  string `json:"path"`
+	Token string `json:"token,omitempty"` // Reserva de frames a confirmar con la carga
 }
 
 type InstructionRequest struct {
@@ -104,7 +120,24 @@ type SwapEntry struct {
 var ProcessSwapTable = make(map[uint]SwapEntry)
 
 type PIDRequest struct {
-	PID uint `json:"pid"`
+	PID   uint   `json:"pid"`
+	Token string `json:"token,omitempty"` // Reserva de frames a confirmar al sacar el proceso de SWAP
+}
+
+// Para reservar frames antes de cargar un proceso o sacarlo de SWAP
+type ReservationRequest struct {
+	PID  uint `json:"pid"`
+	Size int  `json:"size"`
+}
+
+type ReservationResponse struct {
+	Token  string `json:"token"`
+	Frames int    `json:"frames"`
+	TTLMs  int    `json:"ttl_ms"`
+}
+
+type ReleaseReservationRequest struct {
+	Token string `json:"token"`
 }
 
 // Para buscar frames ocupados
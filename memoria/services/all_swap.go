@@ -144,7 +144,8 @@ func PutProcessInSwap(pid uint) error {
 	return nil
 }
 
-func RemoveProcessInSwap(pid uint) error {
+// RemoveProcessInSwap trae el proceso desde SWAP. Si el Kernel reservó frames antes, token los identifica.
+func RemoveProcessInSwap(pid uint, token string) error {
 	memorySwapMutex.Lock()
 	defer memorySwapMutex.Unlock()
 	swapDelay := time.Duration(models.MemoryConfig.SwapDelay) * time.Millisecond
@@ -180,6 +181,7 @@ func RemoveProcessInSwap(pid uint) error {
 
 	// VALIDACIÓN 2: Verificar que el proceso no está ya en frames
 	if alreadyInFrames {
+		ReleaseReservation(token)
 		slog.Warn("Memoria: Proceso ya está en frames, removiendo de swap conceptualmente", "PID", pid)
 		models.ProcessDataLock.Lock()
 		delete(models.ProcessSwapTable, pid)
@@ -196,6 +198,7 @@ func RemoveProcessInSwap(pid uint) error {
 
 	// CASO ESPECIAL: Proceso con 0 frames
 	if swapEntry.Size == 0 {
+		ReleaseReservation(token)
 		models.ProcessDataLock.Lock()
 		models.ProcessFramesTable[pid] = &models.ProcessFrames{PID: pid, Frames: []int{}}
 		delete(models.ProcessSwapTable, pid)
@@ -208,30 +211,10 @@ func RemoveProcessInSwap(pid uint) error {
 	}
 
 	framesNeeded := int(swapEntry.Size / frameSize)
-	var freeFrames []int
-
-	models.UMemoryLock.Lock()
-	slog.Debug("UMemoryLock lockeado SWAP OUT - buscar frames")
-
-	for idx, free := range models.FreeFrames {
-		if free {
-			freeFrames = append(freeFrames, idx)
-			if len(freeFrames) == framesNeeded {
-				break
-			}
-		}
-	}
-
-	if len(freeFrames) < framesNeeded {
-		models.UMemoryLock.Unlock()
-		return fmt.Errorf("no hay suficientes frames libres para des-suspender el proceso PID %d", pid)
-	}
-
-	//Reservar frames
-	for _, frameIdx := range freeFrames {
-		models.FreeFrames[frameIdx] = false
+	freeFrames, err := takeFrames(pid, token, framesNeeded)
+	if err != nil {
+		return fmt.Errorf("%w para des-suspender el proceso PID %d", err, pid)
 	}
-	models.UMemoryLock.Unlock()
 
 	file, err := os.Open(models.MemoryConfig.SwapFilePath)
 	if err != nil {
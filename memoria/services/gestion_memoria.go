@@ -10,7 +10,9 @@ import (
 	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/memoria/models"
 )
 
-func ReserveMemory(pid uint, size int, path string) error {
+// ReserveMemory carga el proceso. Si el Kernel reservó frames antes, token los identifica y se usan esos;
+// si la carga falla antes de tomarlos, la reserva sigue vigente para que el Kernel la libere.
+func ReserveMemory(pid uint, size int, path string, token string) error {
 	if size < 0 {
 		return fmt.Errorf("el tamaño del proceso debe ser mayor a 0 (PID %d)", pid)
 	}
@@ -23,43 +25,16 @@ func ReserveMemory(pid uint, size int, path string) error {
 		return fmt.Errorf("falló la carga de instrucciones para el PID %d", pid)
 	}
 
-	models.UMemoryLock.RLock()
-	slog.Debug("UMemoryLock lockeado RESERVE MEMORY")
-	freeFramesCount := CountFreeFrames()
-	models.UMemoryLock.RUnlock()
-
-	if freeFramesCount < pageCount {
-		return fmt.Errorf("no hay suficientes frames libres para el proceso PID %d", pid)
-	}
-	assignedFrames := make([]int, 0, pageCount)
-
-	models.UMemoryLock.Lock()
-	slog.Debug("UMemoryLock lockeado RESERVE MEMORY 2")
-	// Re-verificamos la disponibilidad de frames dentro del lock de escritura
-	if CountFreeFrames() < pageCount {
-		return fmt.Errorf("condición de carrera detectada: no hay suficientes frames libres para el proceso PID %d", pid)
+	assignedFrames, err := takeFrames(pid, token, pageCount)
+	if err != nil {
+		return fmt.Errorf("%w para el proceso PID %d", err, pid)
 	}
 
-	for i := 0; i < pageCount; i++ {
-		frame := AllocateFrame()
-		if frame == -1 {
-			for _, f := range assignedFrames {
-				models.FreeFrames[f] = true
-			}
-			return fmt.Errorf("falló la asignación de frames para PID %d", pid)
-		}
-		assignedFrames = append(assignedFrames, frame)
-	}
-	models.UMemoryLock.Unlock()
 	models.ProcessDataLock.Lock()
 	if _, exists := models.ProcessTable[pid]; exists {
 		models.ProcessDataLock.Unlock()
 		// Rollback frames asignados
-		models.UMemoryLock.Lock()
-		for _, frame := range assignedFrames {
-			models.FreeFrames[frame] = true
-		}
-		models.UMemoryLock.Unlock()
+		releaseFrames(assignedFrames)
 		return fmt.Errorf("proceso PID %d ya existe", pid)
 	}
 	initializePageTables(pid)
@@ -19,17 +19,46 @@ var (
 
 func GeInstruction(pid uint, pc uint) (string, bool, error) {
 	models.ProcessDataLock.RLock()
+	defer models.ProcessDataLock.RUnlock()
 	instructions, exists := models.InstructionsMap[pid]
 	if !exists || pc >= uint(len(instructions)) {
 		return "", false, errors.New("instruction not found or PC out of bounds")
 	}
-	defer models.ProcessDataLock.RUnlock()
 	instruction := instructions[pc]
 	isLast := pc == uint(len(instructions))-1
 	IncrementMetric(pid, "fetch")
 	return instruction, isLast, nil
 }
 
+// GetInstructionWindow devuelve hasta count instrucciones a partir del PC indicado junto con
+// la cantidad total de instrucciones del proceso. No incrementa la métrica de fetch: la CPU
+// informa cuántas instrucciones consumió realmente con AddInstructionFetches.
+func GetInstructionWindow(pid uint, pc int, count int) ([]string, int, error) {
+	models.ProcessDataLock.RLock()
+	defer models.ProcessDataLock.RUnlock()
+	instructions, exists := models.InstructionsMap[pid]
+	if !exists || pc < 0 || pc >= len(instructions) || count <= 0 {
+		return nil, 0, errors.New("instruction not found or PC out of bounds")
+	}
+
+	end := pc + count
+	if end > len(instructions) {
+		end = len(instructions)
+	}
+	window := make([]string, end-pc)
+	copy(window, instructions[pc:end])
+	return window, len(instructions), nil
+}
+
+// AddInstructionFetches suma a la métrica de fetch las instrucciones que la CPU consumió de su buffer.
+func AddInstructionFetches(pid uint, count int) {
+	models.ProcessDataLock.Lock()
+	defer models.ProcessDataLock.Unlock()
+	for i := 0; i < count; i++ {
+		IncrementMetric(pid, "fetch")
+	}
+}
+
 func GetInstructionsByName(pid uint, scriptName string, instructionsMap map[uint][]string, scriptsPath string) error {
 	path, err := FindScriptByName(scriptsPath, scriptName)
 	if err != nil {
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-2025-1c-Los-magiOS/memoria/models"
+)
+
+const defaultReservationTTL = 30000 // Milisegundos, si la configuración no indica otro valor.
+
+var ErrNotEnoughFrames = errors.New("no hay suficientes frames libres")
+
+// frameReservation son frames apartados para un proceso. Quedan marcados como ocupados, así ninguna otra
+// carga ni des-suspensión los cuenta como libres, hasta que se confirman, se liberan o vence la reserva.
+type frameReservation struct {
+	pid    uint
+	frames []int
+	timer  *time.Timer
+}
+
+var (
+	reservationsMutex sync.Mutex
+	reservations      = make(map[string]*frameReservation)
+	reservationSeq    uint64
+)
+
+// ReservationTTL es cuánto espera una reserva su confirmación antes de devolver los frames.
+func ReservationTTL() time.Duration {
+	ttl := models.MemoryConfig.ReservationTTL
+	if ttl <= 0 {
+		ttl = defaultReservationTTL
+	}
+	return time.Duration(ttl) * time.Millisecond
+}
+
+// ReserveFrames aparta los frames que necesita un proceso del tamaño indicado y devuelve el token con el que
+// se confirman. Verificar y apartar ocurre bajo el mismo lock, así dos pedidos no pueden contar los mismos frames.
+func ReserveFrames(pid uint, size int) (string, int, error) {
+	if size < 0 {
+		return "", 0, fmt.Errorf("el tamaño del proceso debe ser mayor a 0 (PID %d)", pid)
+	}
+	pageCount := int(math.Ceil(float64(size) / float64(models.MemoryConfig.PageSize)))
+
+	frames, err := allocateFrames(pageCount)
+	if err != nil {
+		return "", 0, err
+	}
+
+	reservationsMutex.Lock()
+	reservationSeq++
+	token := fmt.Sprintf("%d-%d", pid, reservationSeq)
+	reservation := &frameReservation{pid: pid, frames: frames}
+	reservation.timer = time.AfterFunc(ReservationTTL(), func() {
+		if ReleaseReservation(token) {
+			slog.Warn("Memoria: Reserva de frames vencida sin confirmar", "PID", pid, "token", token)
+		}
+	})
+	reservations[token] = reservation
+	reservationsMutex.Unlock()
+
+	slog.Debug("Memoria: Frames reservados", "PID", pid, "token", token, "frames", pageCount)
+	return token, pageCount, nil
+}
+
+// TakeReservation confirma la reserva y devuelve sus frames, que pasan a ser del proceso.
+// Devuelve false si el token no existe, venció o pertenece a otro proceso.
+func TakeReservation(token string, pid uint) ([]int, bool) {
+	reservationsMutex.Lock()
+	defer reservationsMutex.Unlock()
+
+	reservation, exists := reservations[token]
+	if !exists || reservation.pid != pid {
+		return nil, false
+	}
+	reservation.timer.Stop()
+	delete(reservations, token)
+	slog.Debug("Memoria: Reserva de frames confirmada", "PID", pid, "token", token)
+	return reservation.frames, true
+}
+
+// ReleaseReservation devuelve los frames de la reserva a la lista de libres. Devuelve false si no existía.
+func ReleaseReservation(token string) bool {
+	reservationsMutex.Lock()
+	reservation, exists := reservations[token]
+	if exists {
+		reservation.timer.Stop()
+		delete(reservations, token)
+	}
+	reservationsMutex.Unlock()
+	if !exists {
+		return false
+	}
+
+	releaseFrames(reservation.frames)
+	slog.Debug("Memoria: Reserva de frames liberada", "PID", reservation.pid, "token", token, "frames", len(reservation.frames))
+	return true
+}
+
+func releaseFrames(frames []int) {
+	models.UMemoryLock.Lock()
+	for _, frame := range frames {
+		models.FreeFrames[frame] = true
+	}
+	models.UMemoryLock.Unlock()
+}
+
+// takeFrames devuelve los frames de la reserva o, si no hay token o ya venció, aparta frames libres en el momento.
+func takeFrames(pid uint, token string, pageCount int) ([]int, error) {
+	if token != "" {
+		frames, ok := TakeReservation(token, pid)
+		if ok && len(frames) == pageCount {
+			return frames, nil
+		}
+		if ok {
+			releaseFrames(frames)
+		}
+		slog.Warn("Memoria: Reserva de frames inválida o vencida, se asignan frames libres", "PID", pid, "token", token)
+	}
+	return allocateFrames(pageCount)
+}
+
+// allocateFrames marca como ocupados pageCount frames libres, o ninguno si no alcanzan.
+func allocateFrames(pageCount int) ([]int, error) {
+	models.UMemoryLock.Lock()
+	defer models.UMemoryLock.Unlock()
+	slog.Debug("UMemoryLock lockeado ALLOCATE FRAMES")
+	if CountFreeFrames() < pageCount {
+		return nil, ErrNotEnoughFrames
+	}
+	frames := make([]int, 0, pageCount)
+	for i := 0; i < pageCount; i++ {
+		frames = append(frames, AllocateFrame())
+	}
+	return frames, nil
+}
@@ -19,9 +19,10 @@ type List[T any] interface {
 	Pop() (T, error)                                     // Remover el último elemento de la lista
 	Remove(index int)                                    // Eliminar un elemento en el índice dado
 	RemoveWhere(match func(T) bool)
-	Set(index int, newValue T) error // Modifica el valor de un elemento de la lista a partir de su índice.
-	Size() int                       // Retornar el tamaño de la lista
-	Sort(less func(a, b T) bool)     // Ordena una Lista de acuerdo al criterio
+	Take(match func(T) bool) (T, int, bool) // Remover y devolver el primer elemento que cumpla el predicado y su índice
+	Set(index int, newValue T) error        // Modifica el valor de un elemento de la lista a partir de su índice.
+	Size() int                              // Retornar el tamaño de la lista
+	Sort(less func(a, b T) bool)            // Ordena una Lista de acuerdo al criterio
 }
 
 // ArrayList implements List
@@ -289,6 +290,34 @@ func (list *ArrayList[T]) RemoveWhere(match func(T) bool) {
 	}
 }
 
+// Take elimina y devuelve el primer elemento que cumple el predicado y el índice que ocupaba, en una sola
+// operación. Si ningún elemento lo cumple retorna el valor "cero" del tipo T, -1 y false.
+//
+// Ejemplo:
+//
+//	func main() {
+//		list := &ArrayList[int]{}
+//		list.Add(10)
+//		list.Add(20)
+//
+//		number, index, taken := list.Take(func(number int) bool {
+//			return number == 20
+//		}) // 20, 1, true y la lista queda [10]
+//	}
+func (list *ArrayList[T]) Take(match func(T) bool) (T, int, bool) {
+	list.mu.Lock() // Bloqueo exclusivo para evitar cambios simultáneos
+	defer list.mu.Unlock()
+
+	for i, item := range list.items {
+		if match(item) {
+			list.items = append(list.items[:i], list.items[i+1:]...)
+			return item, i, true
+		}
+	}
+	var zero T
+	return zero, -1, false
+}
+
 // Set modifica el valor de un elemento de la lista a partir de su índice.
 //
 // Parámetros:
@@ -231,3 +231,31 @@ func TestArrayList_Find(t *testing.T) {
 		t.Errorf("Expected to find 20 at index %d, got %d", index, number)
 	}
 }
+
+func TestArrayList_Take(t *testing.T) {
+	list := &ArrayList[int]{}
+
+	list.Add(10)
+	list.Add(20)
+	list.Add(30)
+
+	number, index, taken := list.Take(func(number int) bool {
+		return number == 20
+	})
+
+	if !taken || number != 20 || index != 1 {
+		t.Errorf("Expected to take 20 at index 1, got %d at %d (%v)", number, index, taken)
+	}
+
+	if list.Size() != 2 {
+		t.Errorf("Expected size 2, got %d", list.Size())
+	}
+
+	_, _, taken = list.Take(func(number int) bool {
+		return number == 20
+	})
+
+	if taken {
+		t.Errorf("Expected 20 to be already taken")
+	}
+}
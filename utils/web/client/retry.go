@@ -0,0 +1,55 @@
+package client
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Backoff calcula la espera entre reintentos: empieza en Initial y se duplica hasta Max.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	current time.Duration
+}
+
+// Next devuelve la próxima espera y duplica la siguiente.
+func (b *Backoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.Initial
+	}
+	wait := b.current
+	b.current *= 2
+	if b.current > b.Max {
+		b.current = b.Max
+	}
+	return wait
+}
+
+// Reset vuelve la espera al valor inicial, después de un intento exitoso.
+func (b *Backoff) Reset() {
+	b.current = 0
+}
+
+// RetryWithBackoff ejecuta operation hasta que no devuelva error, esperando entre intentos según backoff.
+// Devuelve false si se cerró stop antes de lograrlo.
+//
+// Ejemplo:
+//
+//	backoff := client.Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second}
+//	client.RetryWithBackoff(func() error { return registrar() }, &backoff, stop)
+func RetryWithBackoff(operation func() error, backoff *Backoff, stop <-chan struct{}) bool {
+	defer backoff.Reset()
+	for {
+		err := operation()
+		if err == nil {
+			return true
+		}
+		wait := backoff.Next()
+		slog.Warn("Falló el intento, se reintenta", "espera", wait, "error", err)
+		select {
+		case <-stop:
+			return false
+		case <-time.After(wait):
+		}
+	}
+}
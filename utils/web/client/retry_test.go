@@ -0,0 +1,51 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	backoff := Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond}
+	expected := []time.Duration{1, 2, 4, 5, 5}
+	for i, want := range expected {
+		if got := backoff.Next(); got != want*time.Millisecond {
+			t.Errorf("Intento %d: expected %v, got %v", i, want*time.Millisecond, got)
+		}
+	}
+
+	backoff.Reset()
+	if got := backoff.Next(); got != time.Millisecond {
+		t.Errorf("Expected %v after Reset, got %v", time.Millisecond, got)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	attempts := 0
+	operation := func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("todavía no")
+		}
+		return nil
+	}
+
+	backoff := Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+	if !RetryWithBackoff(operation, &backoff, nil) {
+		t.Error("Expected RetryWithBackoff to succeed")
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryWithBackoff_Stop(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+
+	backoff := Backoff{Initial: time.Hour, Max: time.Hour}
+	if RetryWithBackoff(func() error { return errors.New("siempre falla") }, &backoff, stop) {
+		t.Error("Expected RetryWithBackoff to stop")
+	}
+}